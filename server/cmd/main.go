@@ -1,35 +1,144 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	stdlog "log"
+	"os"
+	"os/signal"
 	_ "server/docs"
+	"server/internal/config"
 	"server/internal/handler"
 	logger "server/internal/log"
+	"server/internal/mail"
+	"server/internal/metrics"
+	"server/internal/ratelimit"
 	"server/internal/repository/postgres"
+	"server/internal/service"
+	"server/internal/storage"
+	"server/internal/tracing"
+	"server/pkg"
 	"server/util"
-	//"server/util"
+	"syscall"
+	"time"
 )
 
 // @title Kotiki API
 // @version 1.0
+// @description Ошибки возвращаются в формате RFC 7807 (application/problem+json). Поле code содержит
+// @description стабильный код ошибки, type - URI вида /problems/{code}, request_id - ID запроса из заголовка X-Request-ID.
+// @description Для ошибок проверки (422) поле errors содержит ошибки отдельных полей запроса
 // @BasePath /api
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name Authorization
 func main() {
+	// Загрузка конфигурации
+	cfg, err := config.Load()
+	if err != nil {
+		stdlog.Fatalf("could not load config: %s", err)
+	}
 	// Инициализация логера
-	log := logger.InitLogger()
+	log, logFile := logger.InitLogger(cfg)
+	// Инициализация трассировки OpenTelemetry
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatal().Msg(fmt.Sprintf("could not initialize tracing: %s", err))
+	}
 	// Инициализация бд
-	db, err := postgres.NewDatabase()
+	db, err := postgres.NewDatabase(cfg.Postgres)
 	if err != nil {
 		log.Fatal().Msg(fmt.Sprintf("could not initialize database connection: %s", err))
 	}
+	log.Info().Msg("successfully connected to database")
+	// Статистика пула соединений для /metrics
+	metrics.RegisterDB(db, cfg.Postgres.Name)
+	// Подкоманда управления миграциями: main migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:]); err != nil {
+			log.Fatal().Msg(fmt.Sprintf("migrate: %s", err))
+		}
+		return
+	}
+	// Применение миграций
+	if err := postgres.MigrateUp(db); err != nil {
+		log.Fatal().Msg(fmt.Sprintf("could not apply migrations: %s", err))
+	}
+	// Хранилища PostgreSQL с ограничением времени запросов
+	repos := postgres.NewRepositories(db, time.Duration(cfg.Postgres.QueryTimeout)*time.Second)
+	// Подкоманда назначения роли: main set-role <email> <role>
+	if len(os.Args) > 1 && os.Args[1] == "set-role" {
+		if err := runSetRole(repos, cfg, os.Args[2:]); err != nil {
+			log.Fatal().Msg(fmt.Sprintf("set-role: %s", err))
+		}
+		return
+	}
+	// Инициализация хранилища изображений
+	store, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatal().Msg(fmt.Sprintf("could not initialize storage: %s", err))
+	}
+	// Подкоманда переноса изображений в хранилище: main storage migrate [from-dir]
+	if len(os.Args) > 1 && os.Args[1] == "storage" {
+		if err := runStorage(db, cfg, store, os.Args[2:]); err != nil {
+			log.Fatal().Msg(fmt.Sprintf("storage: %s", err))
+		}
+		return
+	}
 	// Создание директорий для временных файлов
 	util.CreateDirectory()
-	// Инициализация ручек
-	handlers := handler.NewHandler(db, log)
+	// Инициализация ключей подписи токенов
+	jwt, err := pkg.NewJWT(cfg.JWT.Issuer, cfg.JWT.Audience, cfg.JWT.KeysDir, cfg.JWT.ActiveKeyID,
+		cfg.App.SigningKey)
+	if err != nil {
+		log.Fatal().Msg(fmt.Sprintf("could not load jwt signing keys: %s", err))
+	}
+	// Хранилище счетчиков попыток входа: в памяти или общее в Redis
+	limitStore, err := ratelimit.NewStore(cfg.RateLimit)
+	if err != nil {
+		log.Fatal().Msg(fmt.Sprintf("could not initialize rate limit store: %s", err))
+	}
+	// Отправка писем: в лог или через SMTP
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		log.Fatal().Msg(fmt.Sprintf("could not initialize mailer: %s", err))
+	}
+	// Бизнес-логика поверх хранилищ PostgreSQL
+	services := service.New(repos, store, jwt, mailer, cfg)
+	handlers := handler.NewHandler(db, log, cfg, jwt, store, ratelimit.NewLimiter(limitStore, cfg.RateLimit), services)
+	// Остановка по SIGINT и SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Синхронизация кэша отозванных токенов
+	go handlers.RunDenylistSync(ctx)
+	// Удаление истекших токенов подтверждения email
+	go handlers.RunVerificationCleanup(ctx)
+
+	// Запуск сервера до сигнала остановки
+	serverErr := runServer(ctx, handlers.Router(), cfg.Server, log, handlers.Drain)
+	if serverErr != nil {
+		log.Error().Msg(fmt.Sprintf("server: %s", serverErr))
+	}
 
-	// Запуск сервера
-	app := handlers.Router()
-	app.Listen(":8080")
+	// Пул соединений закрывается после завершения запросов, которые им пользуются
+	if err := db.Close(); err != nil {
+		log.Error().Msg(fmt.Sprintf("could not close database connection: %s", err))
+	}
+	if err := limitStore.Close(); err != nil {
+		log.Error().Msg(fmt.Sprintf("could not close rate limit store: %s", err))
+	}
+	// Отправка накопленных спанов
+	tracingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Error().Msg(fmt.Sprintf("could not flush traces: %s", err))
+	}
+	cancel()
+	log.Info().Msg("server stopped")
+	if err := logFile.Close(); err != nil {
+		stdlog.Printf("could not close log file: %s", err)
+	}
+
+	if serverErr != nil {
+		os.Exit(1)
+	}
 }
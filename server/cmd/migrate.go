@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"os"
+	"server/internal/repository/postgres"
+	"strconv"
+	"text/tabwriter"
+)
+
+// runMigrate Обработка подкоманды migrate: up, down [N], status
+func runMigrate(db *sqlx.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: main migrate up | down [steps] | status")
+	}
+
+	switch args[0] {
+	case "up":
+		return postgres.MigrateUp(db)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		return postgres.MigrateDown(db, steps)
+	case "status":
+		statuses, err := postgres.GetMigrationStatus(db)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/internal/config"
+	"server/internal/entities"
+	"server/internal/repository"
+	"server/internal/service"
+)
+
+// runSetRole Обработка подкоманды set-role: назначение роли пользователю по email.
+// Нужна для назначения первого администратора
+func runSetRole(repos repository.Repositories, cfg *config.Config, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: main set-role <email> user|editor|admin")
+	}
+
+	ctx := context.Background()
+	email, role := args[0], args[1]
+	if !entities.ValidRole(role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+
+	// Ключи подписи не нужны: токены только отзываются
+	users := service.NewUserService(repos.Users)
+	tokens := service.NewTokenService(repos.Tokens, repos.Users, nil, cfg.App)
+
+	user, err := users.UpdateRoleByEmail(ctx, email, role)
+	if errors.Is(err, service.ErrUserNotFound) {
+		return fmt.Errorf("user %s not found", email)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Токены с прежней ролью отзываются при следующей синхронизации кэша отозванных токенов
+	return tokens.RevokeAccess(ctx, user.ID)
+}
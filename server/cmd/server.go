@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+	"server/internal/config"
+	"time"
+)
+
+// runServer Запуск сервера на cfg.Address до отмены ctx. После отмены вызывается drain, через
+// cfg.ShutdownDelay сервер перестает принимать соединения и ждет завершения текущих запросов
+// не дольше cfg.ShutdownTimeout
+func runServer(ctx context.Context, app *fiber.App, cfg config.ServerConfig, log *zerolog.Logger,
+	drain func()) error {
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(cfg.Address)
+	}()
+
+	select {
+	case err := <-listenErr:
+		return fmt.Errorf("could not listen on %s: %w", cfg.Address, err)
+	case <-ctx.Done():
+	}
+
+	log.Info().Msg("shutdown signal received, reporting not ready")
+	drain()
+	time.Sleep(time.Duration(cfg.ShutdownDelay) * time.Second)
+
+	log.Info().Msg("waiting for in-flight requests")
+	if err := app.ShutdownWithTimeout(time.Duration(cfg.ShutdownTimeout) * time.Second); err != nil {
+		return fmt.Errorf("could not finish in-flight requests: %w", err)
+	}
+
+	return nil
+}
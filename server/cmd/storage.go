@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"os"
+	"path/filepath"
+	"server/internal/config"
+	"server/internal/entities"
+	"server/internal/repository/postgres"
+	"server/internal/storage"
+)
+
+// runStorage Обработка подкоманды storage: main storage migrate [from-dir].
+// migrate переносит файлы изображений с локального диска в текущее хранилище (storage.driver).
+// Для котов, загруженных до появления размеров изображений, файл из image_path обрабатывается
+// заново, для остальных недостающие файлы копируются из from-dir (по умолчанию storage.dir)
+func runStorage(db *sqlx.DB, cfg *config.Config, st storage.Storage, args []string) error {
+	if len(args) < 1 || len(args) > 2 || args[0] != "migrate" {
+		return errors.New("usage: main storage migrate [from-dir]")
+	}
+
+	fromDir := cfg.Storage.Dir
+	if len(args) == 2 {
+		fromDir = args[1]
+	}
+
+	ctx := context.Background()
+	cats, err := postgres.DBCatImagesGetAll(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, cat := range *cats {
+		if err := migrateCatImages(ctx, db, cfg, st, fromDir, cat); err != nil {
+			fmt.Fprintf(os.Stderr, "cat %d (%s): %s\n", cat.ID, cat.Breed, err)
+			failed++
+			continue
+		}
+		fmt.Printf("cat %d (%s): ok\n", cat.ID, cat.Breed)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cats failed to migrate", failed, len(*cats))
+	}
+	return nil
+}
+
+// migrateCatImages Перенос изображений одного кота
+func migrateCatImages(ctx context.Context, db *sqlx.DB, cfg *config.Config, st storage.Storage,
+	fromDir string, cat entities.Cat) error {
+	if cat.Images.Full == "" {
+		return migrateLegacyImage(ctx, db, cfg, st, fromDir, cat)
+	}
+
+	for _, key := range []string{cat.Images.Thumbnail, cat.Images.Card, cat.Images.Full} {
+		if key == "" {
+			continue
+		}
+
+		exists, err := st.Exists(ctx, key)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		if err := copyFileToStorage(ctx, st, filepath.Join(fromDir, key), key); err != nil {
+			return err
+		}
+	}
+
+	if cat.ImagePath != cat.Images.Full {
+		return postgres.DBCatImagesUpdate(ctx, db, cat.ID, cat.Images.Full, cat.Images)
+	}
+	return nil
+}
+
+// migrateLegacyImage Создание размеров из исходного файла image_path. Если файла нет по
+// сохраненному пути, он ищется по имени в fromDir
+func migrateLegacyImage(ctx context.Context, db *sqlx.DB, cfg *config.Config, st storage.Storage,
+	fromDir string, cat entities.Cat) error {
+	if cat.ImagePath == "" {
+		return errors.New("no image")
+	}
+
+	src, err := os.Open(cat.ImagePath)
+	if errors.Is(err, os.ErrNotExist) {
+		src, err = os.Open(filepath.Join(fromDir, filepath.Base(cat.ImagePath)))
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	catImages, err := storage.SaveCatImages(ctx, st, src, cfg.Images)
+	if err != nil {
+		return err
+	}
+
+	return postgres.DBCatImagesUpdate(ctx, db, cat.ID, catImages.Full, catImages)
+}
+
+// copyFileToStorage Копирование файла с диска в хранилище под ключом key
+func copyFileToStorage(ctx context.Context, st storage.Storage, path, key string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	return st.Put(ctx, key, f, stat.Size(), "image/jpeg")
+}
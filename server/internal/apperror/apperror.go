@@ -0,0 +1,114 @@
+package apperror
+
+import "errors"
+
+// Виды ошибок предметной области. Проверяются через errors.Is, например
+// errors.Is(err, apperror.ErrNotFound)
+var (
+	ErrBadRequest      = errors.New("bad request")
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrValidation      = errors.New("validation failed")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrForbidden       = errors.New("forbidden")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrUnavailable     = errors.New("service unavailable")
+)
+
+// Error Ошибка предметной области: вид ошибки, стабильный код для клиентов (например, cat_not_found)
+// и сообщение. Исходная ошибка, если есть, доступна через errors.Unwrap и в ответ не попадает
+type Error struct {
+	kind    error
+	code    string
+	message string
+	fields  []FieldError
+	err     error
+}
+
+// FieldError Ошибка проверки отдельного поля запроса
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.err != nil {
+		return e.message + ": " + e.err.Error()
+	}
+	return e.message
+}
+
+// Message Сообщение для клиента без исходной ошибки
+func (e *Error) Message() string {
+	return e.message
+}
+
+// Code Стабильный код ошибки. Коды не меняются при изменении текста сообщений
+func (e *Error) Code() string {
+	return e.code
+}
+
+// Fields Ошибки отдельных полей для ошибок проверки
+func (e *Error) Fields() []FieldError {
+	return e.fields
+}
+
+// Is Ошибка совпадает со своим видом, поэтому errors.Is(err, ErrNotFound) работает для любого NotFound
+func (e *Error) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Kind Вид ошибки
+func (e *Error) Kind() error {
+	return e.kind
+}
+
+// BadRequest Запрос не удалось разобрать
+func BadRequest(code, message string) *Error {
+	return &Error{kind: ErrBadRequest, code: code, message: message}
+}
+
+// NotFound Объект не найден
+func NotFound(code, message string) *Error {
+	return &Error{kind: ErrNotFound, code: code, message: message}
+}
+
+// Conflict Объект уже существует или противоречит текущему состоянию
+func Conflict(code, message string) *Error {
+	return &Error{kind: ErrConflict, code: code, message: message}
+}
+
+// Validation Данные запроса не прошли проверку. fields описывают ошибки отдельных полей
+func Validation(code, message string, fields ...FieldError) *Error {
+	return &Error{kind: ErrValidation, code: code, message: message, fields: fields}
+}
+
+// Unauthorized Пользователь не аутентифицирован
+func Unauthorized(code, message string) *Error {
+	return &Error{kind: ErrUnauthorized, code: code, message: message}
+}
+
+// Forbidden Недостаточно прав
+func Forbidden(code, message string) *Error {
+	return &Error{kind: ErrForbidden, code: code, message: message}
+}
+
+// TooManyRequests Превышен лимит запросов
+func TooManyRequests(code, message string) *Error {
+	return &Error{kind: ErrTooManyRequests, code: code, message: message}
+}
+
+// Unavailable Внешний сервис, нужный для запроса, недоступен
+func Unavailable(code, message string) *Error {
+	return &Error{kind: ErrUnavailable, code: code, message: message}
+}
+
+// Wrap Ошибка вида kind с кодом, сообщением для клиента и исходной ошибкой err
+func Wrap(kind error, code, message string, err error) *Error {
+	return &Error{kind: kind, code: code, message: message, err: err}
+}
@@ -1,15 +1,801 @@
 package config
 
-const (
-	// Application
-	ProductionType  = "dev"
-	SigningKey      = "qwerty"
-	TokenExpiration = "1000"
-
-	// PostgreSQL
-	DBHost     = "postgres"
-	DBPort     = "5432"
-	DBUser     = "postgres"
-	DBPassword = "12345678"
-	DBName     = "kotiki"
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
+
+// Config Конфигурация приложения
+type Config struct {
+	App       AppConfig       `yaml:"app" toml:"app"`
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	JWT       JWTConfig       `yaml:"jwt" toml:"jwt"`
+	Postgres  PostgresConfig  `yaml:"postgres" toml:"postgres"`
+	Storage   StorageConfig   `yaml:"storage" toml:"storage"`
+	Images    ImagesConfig    `yaml:"images" toml:"images"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Mail      MailConfig      `yaml:"mail" toml:"mail"`
+}
+
+// AppConfig Общие настройки приложения
+type AppConfig struct {
+	ProductionType  string `yaml:"production_type" toml:"production_type"`
+	SigningKey      string `yaml:"signing_key" toml:"signing_key"`
+	TokenExpiration int    `yaml:"token_expiration" toml:"token_expiration"` // в часах
+
+	RefreshTokenExpiration int `yaml:"refresh_token_expiration" toml:"refresh_token_expiration"` // в часах
+	RevocationSyncInterval int `yaml:"revocation_sync_interval" toml:"revocation_sync_interval"` // в секундах
+	RequestTimeout         int `yaml:"request_timeout" toml:"request_timeout"`                   // в секундах
+
+	// RequireVerifiedEmail список любимых кошек доступен только пользователям с подтвержденным email
+	RequireVerifiedEmail bool `yaml:"require_verified_email" toml:"require_verified_email"`
+}
+
+// ServerConfig Настройки HTTP сервера. Нулевые таймауты чтения, записи и простоя снимают ограничение
+type ServerConfig struct {
+	Address         string `yaml:"address" toml:"address"`                   // host:port для входящих соединений
+	ReadTimeout     int    `yaml:"read_timeout" toml:"read_timeout"`         // в секундах
+	WriteTimeout    int    `yaml:"write_timeout" toml:"write_timeout"`       // в секундах
+	IdleTimeout     int    `yaml:"idle_timeout" toml:"idle_timeout"`         // в секундах
+	ShutdownDelay   int    `yaml:"shutdown_delay" toml:"shutdown_delay"`     // в секундах
+	ShutdownTimeout int    `yaml:"shutdown_timeout" toml:"shutdown_timeout"` // в секундах
+
+	// ProxyHeader заголовок с адресом клиента от обратного прокси, например X-Real-IP. Читается
+	// только для запросов с адресов из TrustedProxies, если список задан
+	ProxyHeader    string   `yaml:"proxy_header" toml:"proxy_header"`
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"` // IP или подсети CIDR
+}
+
+// JWTConfig Настройки подписи токенов. Если keys_dir не задан, токены подписываются HS256
+// с секретом app.signing_key, иначе закрытыми ключами RSA/Ed25519 из keys_dir (<kid>.pem)
+type JWTConfig struct {
+	Issuer      string   `yaml:"issuer" toml:"issuer"`
+	Audience    []string `yaml:"audience" toml:"audience"`
+	KeysDir     string   `yaml:"keys_dir" toml:"keys_dir"`
+	ActiveKeyID string   `yaml:"active_key_id" toml:"active_key_id"`
+}
+
+// PostgresConfig Настройки подключения к PostgreSQL
+type PostgresConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode"`
+
+	QueryTimeout int `yaml:"query_timeout" toml:"query_timeout"` // в секундах
+}
+
+// StorageConfig Настройки хранения изображений
+type StorageConfig struct {
+	Driver    string   `yaml:"driver" toml:"driver"`         // local или s3
+	Dir       string   `yaml:"dir" toml:"dir"`               // директория для файлов изображений (драйвер local)
+	PublicURL string   `yaml:"public_url" toml:"public_url"` // префикс URL, по которому клиенты получают изображения
+	S3        S3Config `yaml:"s3" toml:"s3"`
+}
+
+// S3Config Настройки S3-совместимого хранилища (AWS S3, MinIO)
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint" toml:"endpoint"` // host:port без схемы
+	Region    string `yaml:"region" toml:"region"`
+	Bucket    string `yaml:"bucket" toml:"bucket"`
+	AccessKey string `yaml:"access_key" toml:"access_key"`
+	SecretKey string `yaml:"secret_key" toml:"secret_key"`
+	UseSSL    bool   `yaml:"use_ssl" toml:"use_ssl"`
+}
+
+// ImagesConfig Ограничения на загружаемые изображения
+type ImagesConfig struct {
+	MaxUploadSize  int      `yaml:"max_upload_size" toml:"max_upload_size"` // в байтах
+	MaxWidth       int      `yaml:"max_width" toml:"max_width"`             // в пикселях
+	MaxHeight      int      `yaml:"max_height" toml:"max_height"`           // в пикселях
+	MaxPixels      int      `yaml:"max_pixels" toml:"max_pixels"`           // ширина * высота
+	AllowedFormats []string `yaml:"allowed_formats" toml:"allowed_formats"` // jpeg, png, gif, webp
+}
+
+// TracingConfig Настройки трассировки OpenTelemetry. Без enabled спаны не экспортируются,
+// но trace_id из входящего traceparent все равно попадает в логи
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled" toml:"enabled"`
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"` // host:port OTLP/HTTP коллектора
+	Insecure    bool    `yaml:"insecure" toml:"insecure"` // без TLS
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"` // доля трассируемых запросов без входящего traceparent
+}
+
+// LogConfig Настройки логирования. Пустые level и output выбираются по production_type:
+// debug и stdout в dev, info и file в prod
+type LogConfig struct {
+	Level    string            `yaml:"level" toml:"level"`   // trace, debug, info, warn, error
+	Format   string            `yaml:"format" toml:"format"` // json или console, только для stdout
+	Output   string            `yaml:"output" toml:"output"` // stdout, file или both
+	File     LogFileConfig     `yaml:"file" toml:"file"`
+	Sampling LogSamplingConfig `yaml:"sampling" toml:"sampling"`
+}
+
+// LogFileConfig Настройки файла лога и его ротации. В файл всегда пишется JSON
+type LogFileConfig struct {
+	Path       string `yaml:"path" toml:"path"`
+	MaxSize    int    `yaml:"max_size" toml:"max_size"` // в мегабайтах
+	MaxAge     int    `yaml:"max_age" toml:"max_age"`   // в днях
+	MaxBackups int    `yaml:"max_backups" toml:"max_backups"`
+	Compress   bool   `yaml:"compress" toml:"compress"`
+}
+
+// LogSamplingConfig Прореживание событий уровней debug и info: за каждый период пишутся первые
+// burst событий, из остальных каждое every-е. Нулевой burst отключает прореживание
+type LogSamplingConfig struct {
+	Burst  int `yaml:"burst" toml:"burst"`
+	Period int `yaml:"period" toml:"period"` // в секундах
+	Every  int `yaml:"every" toml:"every"`
+}
+
+// RateLimitConfig Ограничение попыток входа и регистрации. Попытки считаются в скользящем окне
+// отдельно по IP и по email; после delay_after неудачных входов ответ на каждую следующую попытку
+// задерживается на delay_base, удваиваясь до delay_max, а после lockout_after неудач
+// вход на этот email блокируется на lockout_duration. При недоступном хранилище счетчиков
+// попытки отклоняются, если не включен fail_open
+type RateLimitConfig struct {
+	Enabled  bool            `yaml:"enabled" toml:"enabled"`
+	Backend  string          `yaml:"backend" toml:"backend"` // memory или redis
+	FailOpen bool            `yaml:"fail_open" toml:"fail_open"`
+	Redis    RateLimitRedis  `yaml:"redis" toml:"redis"`
+	IP       RateLimitWindow `yaml:"ip" toml:"ip"`
+	Email    RateLimitWindow `yaml:"email" toml:"email"`
+
+	FailureWindow   int `yaml:"failure_window" toml:"failure_window"` // в секундах
+	DelayAfter      int `yaml:"delay_after" toml:"delay_after"`
+	DelayBase       int `yaml:"delay_base" toml:"delay_base"` // в секундах
+	DelayMax        int `yaml:"delay_max" toml:"delay_max"`   // в секундах
+	LockoutAfter    int `yaml:"lockout_after" toml:"lockout_after"`
+	LockoutDuration int `yaml:"lockout_duration" toml:"lockout_duration"` // в секундах
+}
+
+// RateLimitWindow Не более limit попыток за window секунд
+type RateLimitWindow struct {
+	Limit  int `yaml:"limit" toml:"limit"`
+	Window int `yaml:"window" toml:"window"` // в секундах
+}
+
+// RateLimitRedis Подключение к Redis-совместимому хранилищу счетчиков, общему для всех экземпляров
+type RateLimitRedis struct {
+	Address  string `yaml:"address" toml:"address"` // host:port
+	Password string `yaml:"password" toml:"password"`
+	DB       int    `yaml:"db" toml:"db"`
+}
+
+// MailConfig Настройки отправки писем. Драйвер log только пишет письма в лог и подходит
+// для разработки, smtp отправляет их через SMTP сервер
+type MailConfig struct {
+	Driver string     `yaml:"driver" toml:"driver"` // log или smtp
+	From   string     `yaml:"from" toml:"from"`     // адрес отправителя, например "Kotiki <no-reply@kotiki.ru>"
+	SMTP   SMTPConfig `yaml:"smtp" toml:"smtp"`
+
+	// VerificationURL адрес страницы подтверждения email, токен передается в параметре token
+	VerificationURL        string `yaml:"verification_url" toml:"verification_url"`
+	VerificationExpiration int    `yaml:"verification_expiration" toml:"verification_expiration"` // в часах
+}
+
+// SMTPConfig Подключение к SMTP серверу
+type SMTPConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	Username string `yaml:"username" toml:"username"` // без имени пользователя письма отправляются без аутентификации
+	Password string `yaml:"password" toml:"password"`
+	TLS      string `yaml:"tls" toml:"tls"`         // none, starttls или tls
+	Timeout  int    `yaml:"timeout" toml:"timeout"` // в секундах
+}
+
+// LogLevels Допустимые уровни логирования
+var LogLevels = []string{"trace", "debug", "info", "warn", "error"}
+
+// Load Загрузка конфигурации: значения по умолчанию, затем файл из CONFIG_PATH (если задан),
+// затем переменные окружения. Итоговая конфигурация проверяется на корректность
+func Load() (*Config, error) {
+	cfg := defaultConfig()
+
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loadEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// defaultConfig Значения по умолчанию для локального запуска
+func defaultConfig() *Config {
+	return &Config{
+		App: AppConfig{
+			ProductionType:         "dev",
+			TokenExpiration:        1000,
+			RefreshTokenExpiration: 720,
+			RevocationSyncInterval: 30,
+			RequestTimeout:         30,
+		},
+		Server: ServerConfig{
+			Address:         ":8080",
+			ReadTimeout:     60,
+			WriteTimeout:    60,
+			IdleTimeout:     120,
+			ShutdownTimeout: 30,
+		},
+		JWT: JWTConfig{
+			Issuer:   "kotiki",
+			Audience: []string{"kotiki"},
+		},
+		Postgres: PostgresConfig{
+			Host:         "postgres",
+			Port:         5432,
+			User:         "postgres",
+			Name:         "kotiki",
+			SSLMode:      "disable",
+			QueryTimeout: 5,
+		},
+		Storage: StorageConfig{
+			Driver:    "local",
+			Dir:       "/.tmp",
+			PublicURL: "/api/images",
+		},
+		Images: ImagesConfig{
+			MaxUploadSize:  10 << 20,
+			MaxWidth:       4096,
+			MaxHeight:      4096,
+			MaxPixels:      16_000_000,
+			AllowedFormats: []string{"jpeg", "png", "gif", "webp"},
+		},
+		Log: LogConfig{
+			Format: "json",
+			File: LogFileConfig{
+				Path:       "/var/log/app/iqj.log",
+				MaxSize:    1024,
+				MaxAge:     183,
+				MaxBackups: 5,
+				Compress:   true,
+			},
+			Sampling: LogSamplingConfig{
+				Period: 1,
+				Every:  100,
+			},
+		},
+		Tracing: TracingConfig{
+			Endpoint:    "otel-collector:4318",
+			Insecure:    true,
+			ServiceName: "kotiki-backend",
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Backend: "memory",
+			Redis: RateLimitRedis{
+				Address: "redis:6379",
+			},
+			IP:              RateLimitWindow{Limit: 30, Window: 60},
+			Email:           RateLimitWindow{Limit: 10, Window: 60},
+			FailureWindow:   900,
+			DelayAfter:      3,
+			DelayBase:       1,
+			DelayMax:        10,
+			LockoutAfter:    10,
+			LockoutDuration: 900,
+		},
+		Mail: MailConfig{
+			Driver: "log",
+			From:   "Kotiki <no-reply@kotiki.local>",
+			SMTP: SMTPConfig{
+				Host:    "mailpit",
+				Port:    1025,
+				TLS:     "none",
+				Timeout: 10,
+			},
+			VerificationURL:        "http://localhost/verify-email.html",
+			VerificationExpiration: 24,
+		},
+	}
+}
+
+// loadFile Чтение конфигурации из YAML или TOML файла (формат определяется по расширению)
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("unsupported config file format %q, expected .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadEnv Переопределение значений переменными окружения
+func loadEnv(cfg *Config) error {
+	setString(&cfg.App.ProductionType, "PRODUCTION_TYPE")
+	setString(&cfg.App.SigningKey, "SIGNING_KEY")
+	if err := setInt(&cfg.App.TokenExpiration, "TOKEN_EXPIRATION"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.App.RefreshTokenExpiration, "REFRESH_TOKEN_EXPIRATION"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.App.RevocationSyncInterval, "REVOCATION_SYNC_INTERVAL"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.App.RequestTimeout, "REQUEST_TIMEOUT"); err != nil {
+		return err
+	}
+	if err := setBool(&cfg.App.RequireVerifiedEmail, "REQUIRE_VERIFIED_EMAIL"); err != nil {
+		return err
+	}
+
+	setString(&cfg.Server.Address, "SERVER_ADDRESS")
+	if err := setInt(&cfg.Server.ReadTimeout, "SERVER_READ_TIMEOUT"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Server.WriteTimeout, "SERVER_WRITE_TIMEOUT"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Server.IdleTimeout, "SERVER_IDLE_TIMEOUT"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Server.ShutdownDelay, "SERVER_SHUTDOWN_DELAY"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Server.ShutdownTimeout, "SERVER_SHUTDOWN_TIMEOUT"); err != nil {
+		return err
+	}
+	setString(&cfg.Server.ProxyHeader, "SERVER_PROXY_HEADER")
+	setStringList(&cfg.Server.TrustedProxies, "SERVER_TRUSTED_PROXIES")
+
+	setString(&cfg.JWT.Issuer, "JWT_ISSUER")
+	setStringList(&cfg.JWT.Audience, "JWT_AUDIENCE")
+	setString(&cfg.JWT.KeysDir, "JWT_KEYS_DIR")
+	setString(&cfg.JWT.ActiveKeyID, "JWT_ACTIVE_KEY_ID")
+
+	setString(&cfg.Postgres.Host, "DB_HOST")
+	if err := setInt(&cfg.Postgres.Port, "DB_PORT"); err != nil {
+		return err
+	}
+	setString(&cfg.Postgres.User, "DB_USER")
+	setString(&cfg.Postgres.Password, "DB_PASSWORD")
+	setString(&cfg.Postgres.Name, "DB_NAME")
+	setString(&cfg.Postgres.SSLMode, "DB_SSLMODE")
+	if err := setInt(&cfg.Postgres.QueryTimeout, "DB_QUERY_TIMEOUT"); err != nil {
+		return err
+	}
+
+	setString(&cfg.Storage.Driver, "STORAGE_DRIVER")
+	setString(&cfg.Storage.Dir, "STORAGE_DIR")
+	setString(&cfg.Storage.PublicURL, "STORAGE_PUBLIC_URL")
+	setString(&cfg.Storage.S3.Endpoint, "S3_ENDPOINT")
+	setString(&cfg.Storage.S3.Region, "S3_REGION")
+	setString(&cfg.Storage.S3.Bucket, "S3_BUCKET")
+	setString(&cfg.Storage.S3.AccessKey, "S3_ACCESS_KEY")
+	setString(&cfg.Storage.S3.SecretKey, "S3_SECRET_KEY")
+	if err := setBool(&cfg.Storage.S3.UseSSL, "S3_USE_SSL"); err != nil {
+		return err
+	}
+
+	if err := setInt(&cfg.Images.MaxUploadSize, "IMAGES_MAX_UPLOAD_SIZE"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Images.MaxWidth, "IMAGES_MAX_WIDTH"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Images.MaxHeight, "IMAGES_MAX_HEIGHT"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Images.MaxPixels, "IMAGES_MAX_PIXELS"); err != nil {
+		return err
+	}
+	setStringList(&cfg.Images.AllowedFormats, "IMAGES_ALLOWED_FORMATS")
+
+	if err := setBool(&cfg.Tracing.Enabled, "TRACING_ENABLED"); err != nil {
+		return err
+	}
+	setString(&cfg.Tracing.Endpoint, "TRACING_ENDPOINT")
+	if err := setBool(&cfg.Tracing.Insecure, "TRACING_INSECURE"); err != nil {
+		return err
+	}
+	setString(&cfg.Tracing.ServiceName, "TRACING_SERVICE_NAME")
+	if err := setFloat(&cfg.Tracing.SampleRatio, "TRACING_SAMPLE_RATIO"); err != nil {
+		return err
+	}
+
+	setString(&cfg.Log.Level, "LOG_LEVEL")
+	setString(&cfg.Log.Format, "LOG_FORMAT")
+	setString(&cfg.Log.Output, "LOG_OUTPUT")
+	setString(&cfg.Log.File.Path, "LOG_FILE_PATH")
+	if err := setInt(&cfg.Log.File.MaxSize, "LOG_FILE_MAX_SIZE"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Log.File.MaxAge, "LOG_FILE_MAX_AGE"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Log.File.MaxBackups, "LOG_FILE_MAX_BACKUPS"); err != nil {
+		return err
+	}
+	if err := setBool(&cfg.Log.File.Compress, "LOG_FILE_COMPRESS"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Log.Sampling.Burst, "LOG_SAMPLING_BURST"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Log.Sampling.Period, "LOG_SAMPLING_PERIOD"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Log.Sampling.Every, "LOG_SAMPLING_EVERY"); err != nil {
+		return err
+	}
+
+	if err := setBool(&cfg.RateLimit.Enabled, "RATE_LIMIT_ENABLED"); err != nil {
+		return err
+	}
+	setString(&cfg.RateLimit.Backend, "RATE_LIMIT_BACKEND")
+	if err := setBool(&cfg.RateLimit.FailOpen, "RATE_LIMIT_FAIL_OPEN"); err != nil {
+		return err
+	}
+	setString(&cfg.RateLimit.Redis.Address, "RATE_LIMIT_REDIS_ADDRESS")
+	setString(&cfg.RateLimit.Redis.Password, "RATE_LIMIT_REDIS_PASSWORD")
+	if err := setInt(&cfg.RateLimit.Redis.DB, "RATE_LIMIT_REDIS_DB"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.RateLimit.IP.Limit, "RATE_LIMIT_IP_LIMIT"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.RateLimit.IP.Window, "RATE_LIMIT_IP_WINDOW"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.RateLimit.Email.Limit, "RATE_LIMIT_EMAIL_LIMIT"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.RateLimit.Email.Window, "RATE_LIMIT_EMAIL_WINDOW"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.RateLimit.FailureWindow, "RATE_LIMIT_FAILURE_WINDOW"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.RateLimit.DelayAfter, "RATE_LIMIT_DELAY_AFTER"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.RateLimit.DelayBase, "RATE_LIMIT_DELAY_BASE"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.RateLimit.DelayMax, "RATE_LIMIT_DELAY_MAX"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.RateLimit.LockoutAfter, "RATE_LIMIT_LOCKOUT_AFTER"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.RateLimit.LockoutDuration, "RATE_LIMIT_LOCKOUT_DURATION"); err != nil {
+		return err
+	}
+
+	setString(&cfg.Mail.Driver, "MAIL_DRIVER")
+	setString(&cfg.Mail.From, "MAIL_FROM")
+	setString(&cfg.Mail.VerificationURL, "MAIL_VERIFICATION_URL")
+	if err := setInt(&cfg.Mail.VerificationExpiration, "MAIL_VERIFICATION_EXPIRATION"); err != nil {
+		return err
+	}
+	setString(&cfg.Mail.SMTP.Host, "SMTP_HOST")
+	if err := setInt(&cfg.Mail.SMTP.Port, "SMTP_PORT"); err != nil {
+		return err
+	}
+	setString(&cfg.Mail.SMTP.Username, "SMTP_USERNAME")
+	setString(&cfg.Mail.SMTP.Password, "SMTP_PASSWORD")
+	setString(&cfg.Mail.SMTP.TLS, "SMTP_TLS")
+	if err := setInt(&cfg.Mail.SMTP.Timeout, "SMTP_TIMEOUT"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func setString(dst *string, key string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = v
+	}
+}
+
+// setStringList Список значений через запятую
+func setStringList(dst *[]string, key string) {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*dst = list
+}
+
+func setInt(dst *int, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return fmt.Errorf("%s must be an integer, got %q", key, v)
+	}
+	*dst = n
+
+	return nil
+}
+
+func setFloat(dst *float64, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		return fmt.Errorf("%s must be a number, got %q", key, v)
+	}
+	*dst = f
+
+	return nil
+}
+
+func setBool(dst *bool, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		return fmt.Errorf("%s must be a boolean, got %q", key, v)
+	}
+	*dst = b
+
+	return nil
+}
+
+// Validate Проверка конфигурации, возвращает все найденные ошибки разом
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.App.ProductionType != "dev" && c.App.ProductionType != "prod" {
+		errs = append(errs, fmt.Errorf("app.production_type must be \"dev\" or \"prod\", got %q", c.App.ProductionType))
+	}
+	if c.JWT.KeysDir == "" {
+		if c.App.SigningKey == "" {
+			errs = append(errs, errors.New("app.signing_key is required (SIGNING_KEY) when jwt.keys_dir is not set"))
+		} else if c.IsProduction() && len(c.App.SigningKey) < 32 {
+			errs = append(errs, errors.New("app.signing_key must be at least 32 characters in prod"))
+		}
+	} else if c.JWT.ActiveKeyID == "" {
+		errs = append(errs, errors.New("jwt.active_key_id is required (JWT_ACTIVE_KEY_ID) when jwt.keys_dir is set"))
+	}
+	if c.JWT.Issuer == "" {
+		errs = append(errs, errors.New("jwt.issuer is required (JWT_ISSUER)"))
+	}
+	if c.App.TokenExpiration <= 0 {
+		errs = append(errs, errors.New("app.token_expiration must be positive"))
+	}
+	if c.App.RefreshTokenExpiration <= 0 {
+		errs = append(errs, errors.New("app.refresh_token_expiration must be positive"))
+	}
+	if c.App.RevocationSyncInterval <= 0 {
+		errs = append(errs, errors.New("app.revocation_sync_interval must be positive"))
+	}
+	if c.App.RequestTimeout <= 0 {
+		errs = append(errs, errors.New("app.request_timeout must be positive"))
+	}
+
+	if c.Server.Address == "" {
+		errs = append(errs, errors.New("server.address is required (SERVER_ADDRESS)"))
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		errs = append(errs, errors.New("server.read_timeout, server.write_timeout and server.idle_timeout must not be negative"))
+	}
+	if c.Server.ShutdownDelay < 0 {
+		errs = append(errs, errors.New("server.shutdown_delay must not be negative"))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("server.shutdown_timeout must be positive"))
+	}
+
+	if c.Postgres.Host == "" {
+		errs = append(errs, errors.New("postgres.host is required (DB_HOST)"))
+	}
+	if c.Postgres.Port <= 0 || c.Postgres.Port > 65535 {
+		errs = append(errs, fmt.Errorf("postgres.port must be in range 1-65535, got %d", c.Postgres.Port))
+	}
+	if c.Postgres.User == "" {
+		errs = append(errs, errors.New("postgres.user is required (DB_USER)"))
+	}
+	if c.Postgres.Name == "" {
+		errs = append(errs, errors.New("postgres.name is required (DB_NAME)"))
+	}
+	if c.Postgres.QueryTimeout <= 0 {
+		errs = append(errs, errors.New("postgres.query_timeout must be positive"))
+	}
+	if c.Postgres.QueryTimeout > c.App.RequestTimeout {
+		errs = append(errs, errors.New("postgres.query_timeout must not exceed app.request_timeout"))
+	}
+
+	switch c.Storage.Driver {
+	case "local":
+		if c.Storage.Dir == "" {
+			errs = append(errs, errors.New("storage.dir is required (STORAGE_DIR) for the local driver"))
+		}
+	case "s3":
+		if c.Storage.S3.Endpoint == "" {
+			errs = append(errs, errors.New("storage.s3.endpoint is required (S3_ENDPOINT) for the s3 driver"))
+		}
+		if c.Storage.S3.Bucket == "" {
+			errs = append(errs, errors.New("storage.s3.bucket is required (S3_BUCKET) for the s3 driver"))
+		}
+		if c.Storage.S3.AccessKey == "" || c.Storage.S3.SecretKey == "" {
+			errs = append(errs, errors.New("storage.s3.access_key and storage.s3.secret_key are required (S3_ACCESS_KEY, S3_SECRET_KEY) for the s3 driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage.driver must be \"local\" or \"s3\", got %q", c.Storage.Driver))
+	}
+
+	if c.Images.MaxUploadSize <= 0 {
+		errs = append(errs, errors.New("images.max_upload_size must be positive"))
+	}
+	if c.Images.MaxWidth <= 0 || c.Images.MaxHeight <= 0 {
+		errs = append(errs, errors.New("images.max_width and images.max_height must be positive"))
+	}
+	if c.Images.MaxPixels <= 0 {
+		errs = append(errs, errors.New("images.max_pixels must be positive"))
+	}
+	if len(c.Images.AllowedFormats) == 0 {
+		errs = append(errs, errors.New("images.allowed_formats must not be empty"))
+	}
+	for _, format := range c.Images.AllowedFormats {
+		switch format {
+		case "jpeg", "png", "gif", "webp":
+		default:
+			errs = append(errs, fmt.Errorf("images.allowed_formats: unsupported format %q", format))
+		}
+	}
+
+	if c.Tracing.Enabled {
+		if c.Tracing.Endpoint == "" {
+			errs = append(errs, errors.New("tracing.endpoint is required (TRACING_ENDPOINT) when tracing is enabled"))
+		}
+		if c.Tracing.ServiceName == "" {
+			errs = append(errs, errors.New("tracing.service_name is required (TRACING_SERVICE_NAME) when tracing is enabled"))
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sample_ratio must be in range 0-1, got %v", c.Tracing.SampleRatio))
+	}
+
+	if c.Log.Level != "" && !slices.Contains(LogLevels, c.Log.Level) {
+		errs = append(errs, fmt.Errorf("log.level must be one of %s, got %q", strings.Join(LogLevels, ", "), c.Log.Level))
+	}
+	if c.Log.Format != "json" && c.Log.Format != "console" {
+		errs = append(errs, fmt.Errorf("log.format must be \"json\" or \"console\", got %q", c.Log.Format))
+	}
+	switch c.Log.Output {
+	case "", "stdout":
+	case "file", "both":
+		if c.Log.File.Path == "" {
+			errs = append(errs, errors.New("log.file.path is required (LOG_FILE_PATH) for file output"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("log.output must be \"stdout\", \"file\" or \"both\", got %q", c.Log.Output))
+	}
+	if c.Log.File.MaxSize < 0 || c.Log.File.MaxAge < 0 || c.Log.File.MaxBackups < 0 {
+		errs = append(errs, errors.New("log.file.max_size, log.file.max_age and log.file.max_backups must not be negative"))
+	}
+	if c.Log.Sampling.Burst < 0 {
+		errs = append(errs, errors.New("log.sampling.burst must not be negative"))
+	}
+	if c.Log.Sampling.Burst > 0 && (c.Log.Sampling.Period <= 0 || c.Log.Sampling.Every <= 0) {
+		errs = append(errs, errors.New("log.sampling.period and log.sampling.every must be positive when sampling is enabled"))
+	}
+
+	if c.RateLimit.Enabled {
+		switch c.RateLimit.Backend {
+		case "memory":
+		case "redis":
+			if c.RateLimit.Redis.Address == "" {
+				errs = append(errs, errors.New("rate_limit.redis.address is required (RATE_LIMIT_REDIS_ADDRESS) for the redis backend"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("rate_limit.backend must be \"memory\" or \"redis\", got %q", c.RateLimit.Backend))
+		}
+		if c.RateLimit.IP.Limit <= 0 || c.RateLimit.IP.Window <= 0 ||
+			c.RateLimit.Email.Limit <= 0 || c.RateLimit.Email.Window <= 0 {
+			errs = append(errs, errors.New("rate_limit.ip and rate_limit.email limit and window must be positive"))
+		}
+		if c.RateLimit.FailureWindow <= 0 {
+			errs = append(errs, errors.New("rate_limit.failure_window must be positive"))
+		}
+		if c.RateLimit.DelayAfter < 0 || c.RateLimit.DelayBase < 0 || c.RateLimit.DelayMax < c.RateLimit.DelayBase {
+			errs = append(errs, errors.New("rate_limit.delay_after and rate_limit.delay_base must not be negative, rate_limit.delay_max must not be less than delay_base"))
+		}
+		// Задержка выжидается внутри запроса, после нее еще нужно время на проверку пароля
+		if c.RateLimit.DelayBase > 0 && c.RateLimit.DelayMax >= c.App.RequestTimeout {
+			errs = append(errs, errors.New("rate_limit.delay_max must be less than app.request_timeout"))
+		}
+		if c.RateLimit.LockoutAfter < 0 || c.RateLimit.LockoutDuration < 0 {
+			errs = append(errs, errors.New("rate_limit.lockout_after and rate_limit.lockout_duration must not be negative"))
+		}
+	}
+
+	switch c.Mail.Driver {
+	case "log":
+		// Драйвер log записывает действующие ссылки подтверждения в файл и Loki вместе с остальным логом
+		if c.IsProduction() {
+			errs = append(errs, errors.New("mail.driver must be \"smtp\" in prod: the log driver writes verification links to the log"))
+		}
+	case "smtp":
+		if c.Mail.SMTP.Host == "" {
+			errs = append(errs, errors.New("mail.smtp.host is required (SMTP_HOST) for the smtp driver"))
+		}
+		if c.Mail.SMTP.Port <= 0 || c.Mail.SMTP.Port > 65535 {
+			errs = append(errs, fmt.Errorf("mail.smtp.port must be in range 1-65535, got %d", c.Mail.SMTP.Port))
+		}
+		if c.Mail.SMTP.TLS != "none" && c.Mail.SMTP.TLS != "starttls" && c.Mail.SMTP.TLS != "tls" {
+			errs = append(errs, fmt.Errorf("mail.smtp.tls must be \"none\", \"starttls\" or \"tls\", got %q", c.Mail.SMTP.TLS))
+		}
+		if c.Mail.SMTP.Timeout <= 0 {
+			errs = append(errs, errors.New("mail.smtp.timeout must be positive"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("mail.driver must be \"log\" or \"smtp\", got %q", c.Mail.Driver))
+	}
+	if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+		errs = append(errs, fmt.Errorf("mail.from must be an email address (MAIL_FROM): %w", err))
+	}
+	if u, err := url.Parse(c.Mail.VerificationURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("mail.verification_url must be an absolute URL (MAIL_VERIFICATION_URL), got %q", c.Mail.VerificationURL))
+	}
+	if c.Mail.VerificationExpiration <= 0 {
+		errs = append(errs, errors.New("mail.verification_expiration must be positive"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// IsProduction Запущено ли приложение в боевом окружении
+func (c *Config) IsProduction() bool {
+	return c.App.ProductionType == "prod"
+}
@@ -1,32 +1,111 @@
 package entities
 
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+// Допустимые типы шерсти
+var FurTypes = []string{"Бесшерстная", "Короткошерстная", "Полудлинношерстная", "Длинношерстная"}
+
+// Допустимые темпераменты
+var Tempers = []string{"Спокойный", "Ласковый", "Игривый", "Активный", "Независимый", "Общительный"}
+
+// ValidFur проверка, что тип шерсти входит в список допустимых
+func ValidFur(fur string) bool {
+	return slices.Contains(FurTypes, fur)
+}
+
+// ValidTemper проверка, что темперамент входит в список допустимых
+func ValidTemper(temper string) bool {
+	return slices.Contains(Tempers, temper)
+}
+
 type Cat struct {
-	ID             int    `json:"id" db:"id" example:"7"`
-	Breed          string `json:"breed" db:"breed" example:"Мейн-кун"`
-	Fur            string `json:"fur" db:"fur" example:"Длинношерстная"`
-	Temper         string `json:"temper" db:"temper" example:"Спокойный"`
-	CareComplexity int    `json:"care_complexity" db:"care_complexity" example:"4"`
-	ImagePath      string `json:"image_path" db:"image_path" example:"/images/cat.png"`
+	ID             int       `json:"id" db:"id" example:"7"`
+	Breed          string    `json:"breed" db:"breed" example:"Мейн-кун"`
+	Fur            string    `json:"fur" db:"fur" example:"Длинношерстная"`
+	Temper         string    `json:"temper" db:"temper" example:"Спокойный"`
+	CareComplexity int       `json:"care_complexity" db:"care_complexity" example:"4"`
+	ImagePath      string    `json:"image_path" db:"image_path" example:"/api/images/3f2a9c.jpg"`
+	Images         CatImages `json:"images" db:"images"`
 }
 
+// CatImages размеры изображения кошки. В бд хранятся ключи файлов, в ответах API - URL
+type CatImages struct {
+	Thumbnail string `json:"thumbnail,omitempty" example:"/api/images/3f2a9c0e1b7d4a56c8e9f0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5.jpg"`
+	Card      string `json:"card,omitempty" example:"/api/images/9b8a7c6d5e4f30211a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f7081.jpg"`
+	Full      string `json:"full,omitempty" example:"/api/images/0c1d2e3f405162738495a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e7f8091.jpg"`
+}
+
+// Value сериализация в JSONB
+func (i CatImages) Value() (driver.Value, error) {
+	return json.Marshal(i)
+}
+
+// Scan чтение из JSONB
+func (i *CatImages) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, i)
+	case string:
+		return json.Unmarshal([]byte(v), i)
+	case nil:
+		*i = CatImages{}
+		return nil
+	}
+	return errors.New("unsupported type for CatImages")
+}
+
+// CreateCatRequest поля multipart формы создания кошки, изображение передается отдельным файлом
 type CreateCatRequest struct {
-	Breed          string `form:"breed"`
-	Fur            string `form:"fur"`
-	Temper         string `form:"temper"`
-	CareComplexity int    `form:"care_complexity"`
-	Image          string `form:"image"`
+	Breed          string `form:"breed" validate:"required,max=100"`
+	Fur            string `form:"fur" validate:"required,fur"`
+	Temper         string `form:"temper" validate:"required,temper"`
+	CareComplexity int    `form:"care_complexity" validate:"min=1,max=5"`
 }
 
 type UpdateCatRequest struct {
-	ID             int    `json:"id" db:"id" example:"7"`
-	Breed          string `json:"breed" db:"breed" example:"Мейн-кун"`
-	Fur            string `json:"fur" db:"fur" example:"Длинношерстная"`
-	Temper         string `json:"temper" db:"temper" example:"Спокойный"`
-	CareComplexity int    `json:"care_complexity" db:"care_complexity" example:"4"`
+	ID             int    `json:"id" db:"id" example:"7" validate:"min=1" minimum:"1"`
+	Breed          string `json:"breed" db:"breed" example:"Мейн-кун" validate:"required,max=100" maxLength:"100"`
+	Fur            string `json:"fur" db:"fur" example:"Длинношерстная" validate:"required,fur" enums:"Бесшерстная,Короткошерстная,Полудлинношерстная,Длинношерстная"`
+	Temper         string `json:"temper" db:"temper" example:"Спокойный" validate:"required,temper" enums:"Спокойный,Ласковый,Игривый,Активный,Независимый,Общительный"`
+	CareComplexity int    `json:"care_complexity" db:"care_complexity" example:"4" validate:"min=1,max=5" minimum:"1" maximum:"5"`
 }
 
 type FavoriteCat struct {
-	Breed     string `json:"breed" db:"breed" example:"Мейн-кун"`
-	ID        int    `json:"id" db:"id" example:"7"`
-	ImagePath string `json:"image_path" db:"image_path" example:"/images/cat.png"`
+	Breed     string    `json:"breed" db:"breed" example:"Мейн-кун"`
+	ID        int       `json:"id" db:"id" example:"7"`
+	ImagePath string    `json:"image_path" db:"image_path" example:"/api/images/3f2a9c.jpg"`
+	Images    CatImages `json:"images" db:"images"`
+}
+
+// CatFilter параметры фильтрации, сортировки и пагинации списка кошек
+type CatFilter struct {
+	Fur               string `query:"fur"`
+	Temper            string `query:"temper"`
+	Breed             string `query:"breed"`
+	CareComplexityMin int    `query:"care_complexity_min"`
+	CareComplexityMax int    `query:"care_complexity_max"`
+	Sort              string `query:"sort"`
+	Order             string `query:"order"`
+	Limit             int    `query:"limit"`
+	Offset            int    `query:"offset"`
+	Cursor            string `query:"cursor"`
+}
+
+// CatList страница списка кошек
+type CatList struct {
+	Items      []Cat  `json:"items"`
+	Total      int    `json:"total" example:"42"`
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJ2IjoiNCIsImlkIjoxMn0"`
+}
+
+// CatSearchResult результат полнотекстового поиска кошек
+type CatSearchResult struct {
+	Cat
+	Rank    float64 `json:"rank" db:"rank" example:"0.83"`
+	Snippet string  `json:"snippet" db:"snippet" example:"<mark>Мейн-кун</mark> — Длинношерстная, Спокойный"`
 }
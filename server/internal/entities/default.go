@@ -5,9 +5,28 @@ type Message struct {
 	Message string `json:"message"`
 }
 
-// ErrorResponse Структура для ответов с ошибкой
-type ErrorResponse struct {
-	Error string `json:"error"`
+// Problem Ответ с ошибкой в формате RFC 7807 (application/problem+json)
+type Problem struct {
+	// URI типа ошибки, однозначно соответствует code
+	Type string `json:"type" example:"/problems/cat_not_found"`
+	// Текст статуса HTTP
+	Title  string `json:"title" example:"Not Found"`
+	Status int    `json:"status" example:"404"`
+	// Описание конкретной ошибки для разработчика, не предназначено для разбора
+	Detail   string `json:"detail,omitempty" example:"cat not exists"`
+	Instance string `json:"instance,omitempty" example:"/cat/id/42"`
+	// Стабильный код ошибки для обработки на клиенте
+	Code      string `json:"code" example:"cat_not_found"`
+	RequestID string `json:"request_id,omitempty" example:"6f1c2a9e-3b7d-4e52-9a0f-5d8c1b2e7f34"`
+	// Ошибки отдельных полей запроса, только для ошибок проверки
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError Ошибка проверки поля запроса
+type FieldError struct {
+	Field   string `json:"field" example:"limit"`
+	Code    string `json:"code" example:"out_of_range"`
+	Message string `json:"message" example:"limit must be in range 1-100"`
 }
 
 // Id Структура для ответов с id
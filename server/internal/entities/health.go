@@ -0,0 +1,21 @@
+package entities
+
+// Статусы проверок готовности
+const (
+	HealthStatusOK   = "ok"
+	HealthStatusFail = "fail"
+)
+
+// HealthReport Результат проверки готовности сервиса
+type HealthReport struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+// HealthCheck Результат одной проверки
+type HealthCheck struct {
+	Status string `json:"status" example:"ok"`
+	// Время выполнения проверки в миллисекундах
+	DurationMs float64 `json:"duration_ms" example:"1.7"`
+	Error      string  `json:"error,omitempty" example:"dial tcp 172.18.0.2:5432: connect: connection refused"`
+}
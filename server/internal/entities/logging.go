@@ -0,0 +1,6 @@
+package entities
+
+// LogLevel Уровень логирования
+type LogLevel struct {
+	Level string `json:"level" example:"debug" validate:"required,oneof=trace debug info warn error" enums:"trace,debug,info,warn,error"`
+}
@@ -1,98 +1,76 @@
 package handler
 
 import (
+	"fmt"
 	"github.com/gofiber/fiber/v2"
-	"path/filepath"
+	"server/internal/apperror"
 	"server/internal/entities"
+	"server/internal/images"
 	"server/internal/log"
-	"server/internal/repository/postgres"
-	"strconv"
+	"server/internal/metrics"
 )
 
 // CatCreate
 // @Tags         cat
 // @Summary      Создание записи о кошке
-// @Description  Добавление новой записи о кошке в базу данных с логированием ошибок
+// @Description  Добавление новой записи о кошке в базу данных. Изображение перекодируется без метаданных и сохраняется в нескольких размерах
 // @Accept       multipart/form-data
 // @Produce      json
-// @Param        fur            formData string true "Шерсть кошки"
-// @Param        breed          formData string true "Порода кошки"
-// @Param        care_complexity formData integer true "Сложность ухода за кошкой"
-// @Param        temper         formData string true "Темперамент кошки"
-// @Param        image          formData file true "Изображение кошки"
+// @Param        fur            formData string true "Шерсть кошки" Enums(Бесшерстная, Короткошерстная, Полудлинношерстная, Длинношерстная)
+// @Param        breed          formData string true "Порода кошки" maxlength(100)
+// @Param        care_complexity formData integer true "Сложность ухода за кошкой" minimum(1) maximum(5)
+// @Param        temper         formData string true "Темперамент кошки" Enums(Спокойный, Ласковый, Игривый, Активный, Независимый, Общительный)
+// @Param        image          formData file true "Изображение кошки в формате JPEG, PNG, GIF или WebP"
 // @Success      200 {object} entities.Cat "Успешное создание записи"
-// @Failure      400 {object} entities.ErrorResponse "Некорректные данные"
-// @Failure      500 {object} entities.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure      400 {object} entities.Problem "Некорректные данные"
+// @Failure      401 {object} entities.Problem "Пользователь не авторизован"
+// @Failure      403 {object} entities.Problem "Недостаточно прав"
+// @Failure      409 {object} entities.Problem "Кошка этой породы уже существует"
+// @Failure      413 {object} entities.Problem "Файл или разрешение изображения слишком большие"
+// @Failure      415 {object} entities.Problem "Неподдерживаемый формат изображения"
+// @Failure      422 {object} entities.Problem "Данные не прошли проверку"
+// @Failure      500 {object} entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503 {object} entities.Problem "База данных недоступна"
+// @Failure      504 {object} entities.Problem "Истекло время обработки запроса"
 // @Router       /cat [post]
+// @Security ApiKeyAuth
 func (h *Handler) CatCreate(c *fiber.Ctx) error {
+	req := requestBody[entities.CreateCatRequest](c)
+
 	file, err := c.FormFile("image")
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusBadRequest})
-		logEvent.Msg("failed to retrieve file")
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "failed to retrieve file"})
+		return apperror.Validation("validation_failed", "failed to retrieve file",
+			apperror.FieldError{Field: "image", Code: "required", Message: "image is required"})
 	}
+	metrics.ImageUploadBytes.Add(float64(file.Size))
 
-	if file.Header.Get("Content-Type") != "image/jpeg" {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusBadRequest})
-		logEvent.Msg("only JPEG images are allowed")
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "only JPEG images are allowed"})
+	if file.Size > int64(h.cfg.Images.MaxUploadSize) {
+		metrics.ImageUploads.WithLabelValues(metrics.ResultFailure).Inc()
+		return images.ErrTooLarge
 	}
 
-	path := "/.tmp"
-	savePath := filepath.Join(path, file.Filename)
-
-	if err := c.SaveFile(file, savePath); err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Err(err).Msg("failed to save file")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save file"})
+	cat := entities.Cat{
+		Breed:          req.Breed,
+		Fur:            req.Fur,
+		Temper:         req.Temper,
+		CareComplexity: req.CareComplexity,
 	}
 
-	var cat entities.Cat
-
-	careComp, err := strconv.Atoi(c.FormValue("care_complexity"))
+	src, err := file.Open()
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusBadRequest})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return fmt.Errorf("failed to open file: %w", err)
 	}
+	defer src.Close()
 
-	cat.Fur = c.FormValue("fur")
-	cat.Breed = c.FormValue("breed")
-	cat.CareComplexity = careComp
-	cat.Temper = c.FormValue("temper")
-	cat.ImagePath = savePath
-
-	h.logger.Debug().Msg("call postgres.DBCatExistsBreed")
-	exists, err := postgres.DBCatExistsBreed(h.db, cat.Breed)
+	log.Ctx(c.UserContext()).Debug().Msg("call service.CatService.Create")
+	res, err := h.cats.Create(c.UserContext(), &cat, src)
+	metrics.ImageUploads.WithLabelValues(metrics.Result(err)).Inc()
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-	}
-	if exists {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg("cat already exists")
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "article already exists"})
+		return err
 	}
 
-	h.logger.Debug().Msg("call postgres.DBCatCreate")
-	res, err := postgres.DBCatCreate(h.db, &cat)
-	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-	}
+	h.withImageURLs(&res.ImagePath, &res.Images)
 
-	logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Info", Method: c.Method(),
-		Url: c.OriginalURL(), Status: fiber.StatusOK})
-	logEvent.Msg("success")
 	return c.Status(fiber.StatusOK).JSON(res)
 }
 
@@ -104,31 +82,24 @@ func (h *Handler) CatCreate(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        body           body     entities.UpdateCatRequest true "Данные для обновления кошки"
 // @Success      200 {object}   map[string]string "Успешное обновление записи"
-// @Failure      400 {object}   entities.ErrorResponse "Некорректные данные"
-// @Failure      500 {object}   entities.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure      400 {object}   entities.Problem "Некорректные данные"
+// @Failure      401 {object}   entities.Problem "Пользователь не авторизован"
+// @Failure      403 {object}   entities.Problem "Недостаточно прав"
+// @Failure      404 {object}   entities.Problem "Кошка не найдена"
+// @Failure      500 {object}   entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503 {object}   entities.Problem "База данных недоступна"
+// @Failure      504 {object}   entities.Problem "Истекло время обработки запроса"
 // @Router       /cat [put]
+// @Security ApiKeyAuth
 func (h *Handler) CatUpdate(c *fiber.Ctx) error {
-	var cat entities.UpdateCatRequest
-	err := c.BodyParser(&cat)
-	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusBadRequest})
-		logEvent.Err(err).Msg("invalid request body")
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
-	}
+	cat := requestBody[entities.UpdateCatRequest](c)
 
-	h.logger.Debug().Msg("call postgres.DBCatUpdate")
-	err = postgres.DBCatUpdate(h.db, &cat)
+	log.Ctx(c.UserContext()).Debug().Msg("call service.CatService.Update")
+	err := h.cats.Update(c.UserContext(), cat)
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
-	logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Info", Method: c.Method(),
-		Url: c.OriginalURL(), Status: fiber.StatusOK})
-	logEvent.Msg("success")
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "success"})
 }
 
@@ -140,30 +111,27 @@ func (h *Handler) CatUpdate(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        id   path      int  true  "ID кошки для удаления"
 // @Success      200  {object}  map[string]string "Успешное удаление записи"
-// @Failure      400  {object}  entities.ErrorResponse "Некорректный идентификатор"
-// @Failure      500  {object}  entities.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure      400  {object}  entities.Problem "Некорректный идентификатор"
+// @Failure      401  {object}  entities.Problem "Пользователь не авторизован"
+// @Failure      403  {object}  entities.Problem "Недостаточно прав"
+// @Failure      404  {object}  entities.Problem "Кошка не найдена"
+// @Failure      500  {object}  entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503  {object}  entities.Problem "База данных недоступна"
+// @Failure      504  {object}  entities.Problem "Истекло время обработки запроса"
 // @Router       /cat/id/{id} [delete]
+// @Security ApiKeyAuth
 func (h *Handler) CatDelete(c *fiber.Ctx) error {
 	id, err := c.ParamsInt("id")
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusBadRequest})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return invalidParam("id", err)
 	}
 
-	h.logger.Debug().Msg("call postgres.DBCatDelete")
-	err = postgres.DBCatDelete(h.db, id)
+	log.Ctx(c.UserContext()).Debug().Msg("call service.CatService.Delete")
+	err = h.cats.Delete(c.UserContext(), id)
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
-	logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Info", Method: c.Method(),
-		Url: c.OriginalURL(), Status: fiber.StatusOK})
-	logEvent.Msg("success")
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "success"})
 
 }
@@ -176,54 +144,95 @@ func (h *Handler) CatDelete(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        id   path      int  true  "ID кошки для поиска"
 // @Success      200  {object}  entities.Cat "Успешное получение данных о кошке"
-// @Failure      400  {object}  entities.ErrorResponse "Некорректный идентификатор"
-// @Failure      500  {object}  entities.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure      400  {object}  entities.Problem "Некорректный идентификатор"
+// @Failure      404  {object}  entities.Problem "Кошка не найдена"
+// @Failure      500  {object}  entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503  {object}  entities.Problem "База данных недоступна"
+// @Failure      504  {object}  entities.Problem "Истекло время обработки запроса"
 // @Router       /cat/id/{id} [get]
 func (h *Handler) CatGetByID(c *fiber.Ctx) error {
 	id, err := c.ParamsInt("id")
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusBadRequest})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return invalidParam("id", err)
 	}
 
-	h.logger.Debug().Msg("call postgres.DBCatGetByID")
-	res, err := postgres.DBCatGetByID(h.db, id)
+	log.Ctx(c.UserContext()).Debug().Msg("call service.CatService.GetByID")
+	res, err := h.cats.GetByID(c.UserContext(), id)
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
-	logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Info", Method: c.Method(),
-		Url: c.OriginalURL(), Status: fiber.StatusOK})
-	logEvent.Msg("success")
+	h.withImageURLs(&res.ImagePath, &res.Images)
+
 	return c.Status(fiber.StatusOK).JSON(res)
 }
 
 // CatGetAll
 // @Tags         cat
-// @Summary      Получение списка всех кошек
-// @Description  Получение всех записей о кошках из базы данных с логированием ошибок
+// @Summary      Получение списка кошек
+// @Description  Получение страницы списка кошек с фильтрацией, сортировкой и пагинацией по смещению или курсору. При передаче cursor параметр offset игнорируется
 // @Accept       json
 // @Produce      json
-// @Success      200  {array}   entities.Cat "Успешное получение списка кошек"
-// @Failure      500  {object}  entities.ErrorResponse "Внутренняя ошибка сервера"
+// @Param        fur                  query  string  false  "Тип шерсти"
+// @Param        temper               query  string  false  "Темперамент"
+// @Param        breed                query  string  false  "Подстрока названия породы"
+// @Param        care_complexity_min  query  int     false  "Минимальная сложность ухода"
+// @Param        care_complexity_max  query  int     false  "Максимальная сложность ухода"
+// @Param        sort                 query  string  false  "Поле сортировки" Enums(id, breed, fur, temper, care_complexity) default(id)
+// @Param        order                query  string  false  "Направление сортировки" Enums(asc, desc) default(asc)
+// @Param        limit                query  int     false  "Размер страницы" minimum(1) maximum(100) default(20)
+// @Param        offset               query  int     false  "Смещение" minimum(0)
+// @Param        cursor               query  string  false  "Курсор следующей страницы из next_cursor, действует только с теми же sort и order"
+// @Success      200  {object}  entities.CatList "Успешное получение списка кошек"
+// @Failure      400  {object}  entities.Problem "Некорректные параметры запроса"
+// @Failure      422  {object}  entities.Problem "Параметры запроса не прошли проверку"
+// @Failure      500  {object}  entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503  {object}  entities.Problem "База данных недоступна"
+// @Failure      504  {object}  entities.Problem "Истекло время обработки запроса"
 // @Router       /cat [get]
 func (h *Handler) CatGetAll(c *fiber.Ctx) error {
-	h.logger.Debug().Msg("call postgres.DBCatGetAll")
-	cats, err := postgres.DBCatGetAll(h.db)
+	filter := entities.CatFilter{Sort: "id", Order: "asc", Limit: 20}
+	if err := c.QueryParser(&filter); err != nil {
+		return apperror.Wrap(apperror.ErrBadRequest, "malformed_query", "invalid query parameters", err)
+	}
+
+	log.Ctx(c.UserContext()).Debug().Msg("call service.CatService.List")
+	cats, err := h.cats.List(c.UserContext(), &filter)
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
+	}
+
+	for i := range cats.Items {
+		h.withImageURLs(&cats.Items[i].ImagePath, &cats.Items[i].Images)
 	}
 
-	logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Info", Method: c.Method(),
-		Url: c.OriginalURL(), Status: fiber.StatusOK})
-	logEvent.Msg("success")
 	return c.Status(fiber.StatusOK).JSON(cats)
 }
+
+// CatSearch
+// @Tags         cat
+// @Summary      Поиск кошек
+// @Description  Полнотекстовый поиск по породе, шерсти и темпераменту с учетом русской морфологии и опечаток. Результаты упорядочены по релевантности, совпадения в snippet выделены тегом mark, остальной текст snippet экранирован как HTML
+// @Accept       json
+// @Produce      json
+// @Param        q      query  string  true   "Поисковый запрос"
+// @Param        limit  query  int     false  "Максимальное число результатов" minimum(1) maximum(100) default(20)
+// @Success      200  {array}   entities.CatSearchResult "Результаты поиска"
+// @Failure      422  {object}  entities.Problem "Параметры запроса не прошли проверку"
+// @Failure      500  {object}  entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503  {object}  entities.Problem "База данных недоступна"
+// @Failure      504  {object}  entities.Problem "Истекло время обработки запроса"
+// @Router       /cat/search [get]
+func (h *Handler) CatSearch(c *fiber.Ctx) error {
+	log.Ctx(c.UserContext()).Debug().Msg("call service.CatService.Search")
+	res, err := h.cats.Search(c.UserContext(), c.Query("q"), c.QueryInt("limit", 20))
+	if err != nil {
+		return err
+	}
+
+	for i := range *res {
+		h.withImageURLs(&(*res)[i].ImagePath, &(*res)[i].Images)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(res)
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"server/internal/apperror"
+	"server/internal/entities"
+	"server/internal/images"
+	"server/internal/log"
+	"server/internal/ratelimit"
+	"server/internal/repository/postgres"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// problemTypePrefix Префикс URI типа ошибки, за ним следует код ошибки
+	problemTypePrefix = "/problems/"
+	// mimeProblemJSON Тип содержимого ответов с ошибкой (RFC 7807)
+	mimeProblemJSON = "application/problem+json"
+)
+
+// errorStatuses Коды ответа для видов ошибок предметной области
+var errorStatuses = map[error]int{
+	apperror.ErrBadRequest:      fiber.StatusBadRequest,
+	apperror.ErrNotFound:        fiber.StatusNotFound,
+	apperror.ErrConflict:        fiber.StatusConflict,
+	apperror.ErrValidation:      fiber.StatusUnprocessableEntity,
+	apperror.ErrUnauthorized:    fiber.StatusUnauthorized,
+	apperror.ErrForbidden:       fiber.StatusForbidden,
+	apperror.ErrTooManyRequests: fiber.StatusTooManyRequests,
+	apperror.ErrUnavailable:     fiber.StatusServiceUnavailable,
+}
+
+// handledError Ошибка, ответ на которую уже отправлен. Возвращается дальше по цепочке middleware,
+// чтобы трассировка видела исходную ошибку, а обработчик ошибок приложения не отвечал повторно
+type handledError struct {
+	err error
+}
+
+func (e handledError) Error() string {
+	return e.err.Error()
+}
+
+func (e handledError) Unwrap() error {
+	return e.err
+}
+
+// handleErrors Middleware, в котором ошибка ручки один раз превращается в ответ. Стоит внутри
+// middleware логов, метрик и трассировки, поэтому они читают уже итоговый код ответа
+func (h *Handler) handleErrors(c *fiber.Ctx) error {
+	err := c.Next()
+	if err == nil {
+		return nil
+	}
+
+	if err := h.errorHandler(c, err); err != nil {
+		_ = c.SendStatus(fiber.StatusInternalServerError)
+	}
+	return handledError{err: err}
+}
+
+// appErrorHandler Обработчик ошибок приложения Fiber. Отвечает только на ошибки, которые
+// не прошли через handleErrors, например из внешних middleware
+func (h *Handler) appErrorHandler(c *fiber.Ctx, err error) error {
+	if errors.As(err, &handledError{}) {
+		return nil
+	}
+	return h.errorHandler(c, err)
+}
+
+// errorHandler Единый обработчик ошибок, возвращенных ручками и middleware. Передает ошибку
+// в строку лога доступа и отвечает entities.Problem в формате application/problem+json
+func (h *Handler) errorHandler(c *fiber.Ctx, err error) error {
+	problem := newProblem(err)
+	problem.Instance = c.Path()
+	problem.RequestID, _ = c.Locals(log.RequestIDKey).(string)
+	log.SetError(c, problem.Code, err)
+
+	var exceeded *ratelimit.ExceededError
+	if errors.As(err, &exceeded) {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfterSeconds(exceeded.RetryAfter)))
+	}
+
+	return c.Status(problem.Status).JSON(problem, mimeProblemJSON)
+}
+
+// newProblem Описание ошибки для клиента. Текст внутренних ошибок клиенту не отдается
+func newProblem(err error) *entities.Problem {
+	var problem *entities.Problem
+
+	var appErr *apperror.Error
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &appErr):
+		problem = &entities.Problem{Status: errorStatuses[appErr.Kind()], Code: appErr.Code(), Detail: appErr.Message()}
+		for _, field := range appErr.Fields() {
+			problem.Errors = append(problem.Errors, entities.FieldError{Field: field.Field, Code: field.Code,
+				Message: field.Message})
+		}
+	case errors.As(err, &fiberErr):
+		problem = &entities.Problem{Status: fiberErr.Code, Detail: fiberErr.Message}
+	default:
+		if status, code, detail := imageErrorStatus(err); status != 0 {
+			problem = &entities.Problem{Status: status, Code: code, Detail: detail}
+		} else {
+			problem = &entities.Problem{Status: serverErrorStatus(err)}
+		}
+	}
+
+	problem.Title = utils.StatusMessage(problem.Status)
+	if problem.Code == "" {
+		problem.Code = statusCode(problem.Status)
+	}
+	problem.Type = problemTypePrefix + problem.Code
+
+	return problem
+}
+
+// statusCode Код ошибки по статусу ответа для ошибок без собственного кода, например "not_found"
+func statusCode(status int) string {
+	switch status {
+	case fiber.StatusInternalServerError:
+		return "internal_error"
+	case fiber.StatusServiceUnavailable:
+		return "service_unavailable"
+	case fiber.StatusGatewayTimeout:
+		return "timeout"
+	}
+	return strings.ReplaceAll(strings.ToLower(utils.StatusMessage(status)), " ", "_")
+}
+
+// serverErrorStatus Код ответа для внутренней ошибки: 504 при истечении времени запроса,
+// 503 если бд недоступна, иначе 500
+func serverErrorStatus(err error) int {
+	switch {
+	case postgres.IsTimeout(err):
+		return fiber.StatusGatewayTimeout
+	case postgres.IsUnavailable(err):
+		return fiber.StatusServiceUnavailable
+	}
+	return fiber.StatusInternalServerError
+}
+
+// imageErrorStatus Код ответа, код ошибки и сообщение для клиента при проверке изображения: 413 для
+// слишком большого файла или разрешения, 415 для неподдерживаемого формата, 0 если ошибка не связана
+// с изображением. Сообщение фиксированное, текст ошибки декодера попадает только в лог
+func imageErrorStatus(err error) (int, string, string) {
+	switch {
+	case errors.Is(err, images.ErrTooLarge):
+		return fiber.StatusRequestEntityTooLarge, "image_too_large", "image file is too large"
+	case errors.Is(err, images.ErrDimensionsTooLarge):
+		return fiber.StatusRequestEntityTooLarge, "image_dimensions_too_large", "image dimensions are too large"
+	case errors.Is(err, images.ErrUnsupportedFormat):
+		return fiber.StatusUnsupportedMediaType, "unsupported_image_format", "unsupported image format"
+	case errors.Is(err, images.ErrDecode):
+		return fiber.StatusUnsupportedMediaType, "invalid_image", "unsupported or corrupt image"
+	}
+	return 0, "", ""
+}
+
+// retryAfterSeconds Значение Retry-After в целых секундах с округлением вверх, не меньше 1
+func retryAfterSeconds(d time.Duration) int {
+	return max(int((d+time.Second-1)/time.Second), 1)
+}
+
+// invalidBody Ошибка 400 для тела запроса, которое не удалось разобрать
+func invalidBody(err error) error {
+	return apperror.Wrap(apperror.ErrBadRequest, "invalid_body", "invalid request body", err)
+}
+
+// invalidParam Ошибка 400 для параметра пути, который не удалось разобрать
+func invalidParam(name string, err error) error {
+	return apperror.Wrap(apperror.ErrBadRequest, "invalid_parameter", name+" must be an integer", err)
+}
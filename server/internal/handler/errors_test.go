@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"net/http/httptest"
+	"server/internal/apperror"
+	"server/internal/entities"
+	"server/internal/images"
+	"server/internal/log"
+	"server/internal/metrics"
+	"server/internal/ratelimit"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterSeconds(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{d: 0, want: 1},
+		{d: -time.Second, want: 1},
+		{d: time.Millisecond, want: 1},
+		{d: time.Second, want: 1},
+		{d: time.Second + time.Nanosecond, want: 2},
+		{d: 1500 * time.Millisecond, want: 2},
+		{d: 15 * time.Minute, want: 900},
+	}
+
+	for _, tt := range tests {
+		if got := retryAfterSeconds(tt.d); got != tt.want {
+			t.Errorf("retryAfterSeconds(%s) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestErrorHandlerRetryAfter(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantCode       string
+		wantRetryAfter string
+	}{
+		{
+			name:           "ip limit",
+			err:            rateLimited(&ratelimit.ExceededError{Reason: ratelimit.ReasonIP, RetryAfter: 30 * time.Second}),
+			wantStatus:     fiber.StatusTooManyRequests,
+			wantCode:       "too_many_requests",
+			wantRetryAfter: "30",
+		},
+		{
+			name:           "email limit",
+			err:            rateLimited(&ratelimit.ExceededError{Reason: ratelimit.ReasonEmail, RetryAfter: 1500 * time.Millisecond}),
+			wantStatus:     fiber.StatusTooManyRequests,
+			wantCode:       "too_many_requests",
+			wantRetryAfter: "2",
+		},
+		{
+			name:           "locked",
+			err:            rateLimited(&ratelimit.ExceededError{Reason: ratelimit.ReasonLocked, RetryAfter: 15 * time.Minute}),
+			wantStatus:     fiber.StatusTooManyRequests,
+			wantCode:       "account_locked",
+			wantRetryAfter: "900",
+		},
+		{
+			name:       "other error",
+			err:        fiber.ErrNotFound,
+			wantStatus: fiber.StatusNotFound,
+			wantCode:   "not_found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{}
+			app := fiber.New(fiber.Config{ErrorHandler: h.errorHandler})
+			app.Post("/login", func(c *fiber.Ctx) error {
+				return tt.err
+			})
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/login", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get(fiber.HeaderRetryAfter); got != tt.wantRetryAfter {
+				t.Fatalf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+			var problem entities.Problem
+			if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Code != tt.wantCode {
+				t.Fatalf("code = %q, want %q", problem.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestNewProblemImageDecodeDetail(t *testing.T) {
+	err := fmt.Errorf("%w: %w", images.ErrDecode, errors.New("png: invalid format: not enough pixel data"))
+
+	problem := newProblem(err)
+	if problem.Status != fiber.StatusUnsupportedMediaType || problem.Code != "invalid_image" {
+		t.Fatalf("newProblem() = %d %s, want %d invalid_image", problem.Status, problem.Code,
+			fiber.StatusUnsupportedMediaType)
+	}
+	if problem.Detail != "unsupported or corrupt image" {
+		t.Fatalf("Detail = %q, want fixed message without decoder error", problem.Detail)
+	}
+}
+
+func TestHandleErrorsConvertsOnce(t *testing.T) {
+	h := &Handler{}
+	app := fiber.New(fiber.Config{ErrorHandler: h.appErrorHandler})
+
+	// Внешний middleware, как трассировка: получает исходную ошибку и уже итоговый код ответа
+	var outerErr error
+	var outerStatus int
+	app.Use(func(c *fiber.Ctx) error {
+		outerErr = c.Next()
+		outerStatus = c.Response().StatusCode()
+		return outerErr
+	})
+	app.Use(metrics.Middleware())
+	app.Use(h.handleErrors)
+	app.Get("/cats/:id", func(c *fiber.Ctx) error {
+		return apperror.NotFound("cat_not_found", "cat not found")
+	})
+
+	requests := metrics.HTTPRequests.WithLabelValues(fiber.MethodGet, "/cats/:id", "404")
+	before := testutil.ToFloat64(requests)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/cats/1", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusNotFound)
+	}
+	var problem entities.Problem
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Code != "cat_not_found" {
+		t.Errorf("code = %q, want cat_not_found", problem.Code)
+	}
+
+	if !errors.Is(outerErr, apperror.ErrNotFound) || outerStatus != fiber.StatusNotFound {
+		t.Errorf("outer middleware got %v with status %d, want not found error with status %d",
+			outerErr, outerStatus, fiber.StatusNotFound)
+	}
+	if got := testutil.ToFloat64(requests) - before; got != 1 {
+		t.Errorf("http_requests_total{status=\"404\"} increased by %v, want 1", got)
+	}
+}
+
+func TestRequestLoggerFinalStatus(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+
+	h := &Handler{}
+	app := fiber.New(fiber.Config{ErrorHandler: h.appErrorHandler})
+
+	var outerErr error
+	app.Use(func(c *fiber.Ctx) error {
+		outerErr = c.Next()
+		return outerErr
+	})
+	app.Use(log.RequestLogger(&logger))
+	app.Use(h.handleErrors)
+	app.Get("/cats/:id", func(c *fiber.Ctx) error {
+		return apperror.NotFound("cat_not_found", "cat not found")
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/cats/1", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusNotFound)
+	}
+	if !errors.Is(outerErr, apperror.ErrNotFound) {
+		t.Errorf("RequestLogger returned %v, want not found error", outerErr)
+	}
+
+	var line struct {
+		Level  string `json:"level"`
+		Status int    `json:"status"`
+		Code   string `json:"code"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("access log %q: %v", buf.String(), err)
+	}
+	if line.Status != fiber.StatusNotFound || line.Code != "cat_not_found" || line.Level != "warn" {
+		t.Errorf("access log = %+v, want warn with status 404 and code cat_not_found", line)
+	}
+}
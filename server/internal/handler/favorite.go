@@ -2,9 +2,9 @@ package handler
 
 import (
 	"github.com/gofiber/fiber/v2"
-	"server/internal/entities"
+	"server/internal/apperror"
 	"server/internal/log"
-	"server/internal/repository/postgres"
+	"server/internal/metrics"
 )
 
 // GetFavoriteCats
@@ -14,28 +14,30 @@ import (
 // @Accept       json
 // @Produce      json
 // @Success      200  {array}   entities.FavoriteCat "Успешное получение списка любимых кошек"
-// @Failure      400  {object}  entities.ErrorResponse "Некорректный идентификатор пользователя"
-// @Failure      500  {object}  entities.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure      401  {object}  entities.Problem "Пользователь не авторизован"
+// @Failure      403  {object}  entities.Problem "Email не подтвержден"
+// @Failure      500  {object}  entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503  {object}  entities.Problem "База данных недоступна"
+// @Failure      504  {object}  entities.Problem "Истекло время обработки запроса"
 // @Router       /auth/favorites [get]
 // @Security ApiKeyAuth
 func (h *Handler) GetFavoriteCats(c *fiber.Ctx) error {
 	id, ok := c.Locals("id").(int)
 	if !ok {
-		return c.SendStatus(fiber.StatusForbidden)
+		return apperror.Unauthorized("missing_token", "missing auth token")
 	}
 
-	h.logger.Debug().Msg("call postgres.DBGetFavoriteCats")
-	cats, err := postgres.DBGetFavoriteCats(h.db, int(id))
+	log.Ctx(c.UserContext()).Debug().Msg("call service.FavoriteService.List")
+	cats, err := h.favorites.List(c.UserContext(), id)
+	metrics.FavoriteOperations.WithLabelValues("list", metrics.Result(err)).Inc()
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
+	}
+
+	for i := range *cats {
+		h.withImageURLs(&(*cats)[i].ImagePath, &(*cats)[i].Images)
 	}
 
-	logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Info", Method: c.Method(),
-		Url: c.OriginalURL(), Status: fiber.StatusOK})
-	logEvent.Msg("success")
 	return c.Status(fiber.StatusOK).JSON(cats)
 }
 
@@ -47,67 +49,33 @@ func (h *Handler) GetFavoriteCats(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        id   path      int  true  "ID кошки для добавления в список любимых"
 // @Success      200   {object}  entities.Favorite "Успешное добавление кошки в список любимых"
-// @Failure      400   {object}  entities.ErrorResponse "Некорректные данные запроса"
-// @Failure      500   {object}  entities.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure      400   {object}  entities.Problem "Некорректные данные запроса"
+// @Failure      401   {object}  entities.Problem "Пользователь не авторизован"
+// @Failure      403   {object}  entities.Problem "Email не подтвержден"
+// @Failure      404   {object}  entities.Problem "Кошка не найдена"
+// @Failure      409   {object}  entities.Problem "Кошка уже в списке любимых"
+// @Failure      500   {object}  entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503   {object}  entities.Problem "База данных недоступна"
+// @Failure      504   {object}  entities.Problem "Истекло время обработки запроса"
 // @Router       /auth/favorites/id/{id} [post]
 // @Security ApiKeyAuth
 func (h *Handler) AddFavoriteCat(c *fiber.Ctx) error {
 	id, ok := c.Locals("id").(int)
 	if !ok {
-		return c.SendStatus(fiber.StatusForbidden)
+		return apperror.Unauthorized("missing_token", "missing auth token")
 	}
 
 	catID, err := c.ParamsInt("id")
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
-	}
-
-	favorite := &entities.Favorite{
-		UserID: id,
-		CatID:  catID,
-	}
-
-	h.logger.Debug().Msg("call postgres.DBFavoriteExists")
-	flag, err := postgres.DBFavoriteExists(h.db, favorite)
-	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-	}
-	if flag {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg("favorite already exists")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "favorite already exists"})
-	}
-
-	h.logger.Debug().Msg("call postgres.DBCatExistsID")
-	exists, err := postgres.DBCatExistsID(h.db, catID)
-	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-	}
-	if !exists {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Err(err).Msg("cat not exists")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "cat do not exists"})
+		return invalidParam("id", err)
 	}
 
-	h.logger.Debug().Msg("call postgres.AddFavoriteCat")
-	res, err := postgres.DBAddFavoriteCat(h.db, favorite)
+	log.Ctx(c.UserContext()).Debug().Msg("call service.FavoriteService.Add")
+	res, err := h.favorites.Add(c.UserContext(), id, catID)
+	metrics.FavoriteOperations.WithLabelValues("add", metrics.Result(err)).Inc()
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
-	logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Info", Method: c.Method(),
-		Url: c.OriginalURL(), Status: fiber.StatusOK})
-	logEvent.Msg("success")
 	return c.Status(fiber.StatusOK).JSON(res)
 }
 
@@ -119,66 +87,31 @@ func (h *Handler) AddFavoriteCat(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        id   path      int  true  "ID кошки для удаления из списка любимых"
 // @Success      200   {object}  map[string]string "Успешное удаление кошки из списка любимых"
-// @Failure      400   {object}  entities.ErrorResponse "Некорректные данные запроса"
-// @Failure      500   {object}  entities.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure      400   {object}  entities.Problem "Некорректные данные запроса"
+// @Failure      401   {object}  entities.Problem "Пользователь не авторизован"
+// @Failure      403   {object}  entities.Problem "Email не подтвержден"
+// @Failure      404   {object}  entities.Problem "Кошка не найдена или не в списке любимых"
+// @Failure      500   {object}  entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503   {object}  entities.Problem "База данных недоступна"
+// @Failure      504   {object}  entities.Problem "Истекло время обработки запроса"
 // @Router       /auth/favorites/id/{id} [delete]
 // @Security ApiKeyAuth
 func (h *Handler) DeleteFavoriteCat(c *fiber.Ctx) error {
 	id, ok := c.Locals("id").(int)
 	if !ok {
-		return c.SendStatus(fiber.StatusForbidden)
+		return apperror.Unauthorized("missing_token", "missing auth token")
 	}
 
 	catID, err := c.ParamsInt("id")
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
-	}
-
-	h.logger.Debug().Msg("call postgres.DBCatExistsID")
-	exists, err := postgres.DBCatExistsID(h.db, catID)
-	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-	}
-	if !exists {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Err(err).Msg("cat not exists")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "cat do not exists"})
-	}
-
-	favorite := &entities.Favorite{
-		UserID: id,
-		CatID:  catID,
-	}
-
-	h.logger.Debug().Msg("call postgres.DBFavoriteExists")
-	flag, err := postgres.DBFavoriteExists(h.db, favorite)
-	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-	}
-	if !flag {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg("favorite already exists")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "favorite not exists"})
+		return invalidParam("id", err)
 	}
 
-	h.logger.Debug().Msg("call postgres.DBRemoveFavoriteCat")
-	err = postgres.DBRemoveFavoriteCat(h.db, favorite)
+	log.Ctx(c.UserContext()).Debug().Msg("call service.FavoriteService.Remove")
+	err = h.favorites.Remove(c.UserContext(), id, catID)
+	metrics.FavoriteOperations.WithLabelValues("remove", metrics.Result(err)).Inc()
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
-	logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Info", Method: c.Method(),
-		Url: c.OriginalURL(), Status: fiber.StatusOK})
-	logEvent.Msg("success")
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
 }
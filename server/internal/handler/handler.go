@@ -6,8 +6,16 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog"
 	"server/internal/config"
+	"server/internal/entities"
 	"server/internal/log"
+	"server/internal/metrics"
+	"server/internal/ratelimit"
+	"server/internal/service"
+	"server/internal/storage"
+	"server/internal/tracing"
 	"server/pkg"
+	"sync/atomic"
+	"time"
 
 	//"server/pkg"
 
@@ -17,13 +25,38 @@ import (
 
 // Handler Инициализация структуры ручки
 type Handler struct {
-	db     *sqlx.DB
-	logger *zerolog.Logger
+	db      *sqlx.DB
+	logger  *zerolog.Logger
+	cfg     *config.Config
+	jwt     *pkg.JWT
+	storage storage.Storage
+	limiter *ratelimit.Limiter
+	// draining Сервис останавливается и не должен получать новые запросы
+	draining atomic.Bool
+
+	cats          *service.CatService
+	users         *service.UserService
+	favorites     *service.FavoriteService
+	tokens        *service.TokenService
+	verifications *service.VerificationService
 }
 
 // NewHandler Инициализация экземпляра ручки
-func NewHandler(db *sqlx.DB, logger *zerolog.Logger) *Handler {
-	return &Handler{db: db, logger: logger}
+func NewHandler(db *sqlx.DB, logger *zerolog.Logger, cfg *config.Config, jwt *pkg.JWT,
+	storage storage.Storage, limiter *ratelimit.Limiter, services *service.Services) *Handler {
+	return &Handler{
+		db:            db,
+		logger:        logger,
+		cfg:           cfg,
+		jwt:           jwt,
+		storage:       storage,
+		limiter:       limiter,
+		cats:          services.Cats,
+		users:         services.Users,
+		favorites:     services.Favorites,
+		tokens:        services.Tokens,
+		verifications: services.Verifications,
+	}
 }
 
 // Router Инициализация всех запросов
@@ -31,41 +64,82 @@ func (h *Handler) Router() *fiber.App {
 	f := fiber.New(fiber.Config{
 		CaseSensitive: true,
 		StrictRouting: true,
+		// Запас сверх размера изображения на остальные поля multipart формы
+		BodyLimit:    h.cfg.Images.MaxUploadSize + 1<<20,
+		ReadTimeout:  time.Duration(h.cfg.Server.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(h.cfg.Server.WriteTimeout) * time.Second,
+		IdleTimeout:  time.Duration(h.cfg.Server.IdleTimeout) * time.Second,
+		// Адрес клиента для лимитов попыток входа берется из заголовка обратного прокси
+		ProxyHeader:             h.cfg.Server.ProxyHeader,
+		EnableTrustedProxyCheck: len(h.cfg.Server.TrustedProxies) > 0,
+		TrustedProxies:          h.cfg.Server.TrustedProxies,
+		ErrorHandler:            h.appErrorHandler,
 	})
 
 	// CORS middleware
 	f.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		//AllowCredentials: true,
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, HEAD, PUT, PATCH, POST, DELETE",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, X-Request-ID",
+		AllowMethods:  "GET, HEAD, PUT, PATCH, POST, DELETE",
+		ExposeHeaders: fiber.HeaderXRequestID + ", " + fiber.HeaderRetryAfter,
 	}))
-	f.Use(log.RequestLogger(h.logger)) // Logger middleware
+	// ID запроса из заголовка X-Request-ID или новый, возвращается в ответе и в теле ошибок
+	f.Use(log.RequestID())
+	f.Use(tracing.Middleware())
+	// Логер запроса и строка лога доступа
+	f.Use(log.RequestLogger(h.logger))
+	f.Use(metrics.Middleware())
+	// Ответ на ошибку ручки, внешние middleware получают ошибку и читают итоговый код ответа
+	f.Use(h.handleErrors)
+	f.Use(h.requestTimeout)
 
 	f.Get("/swagger/*", fiberSwagger.WrapHandler)
-	f.Get("/health", func(c *fiber.Ctx) error {
-		return c.Status(fiber.StatusOK).SendString("healthy")
-	})
+	f.Get("/livez", h.Livez)
+	f.Get("/readyz", h.Readyz)
+	f.Get("/metrics", metrics.Handler())
+
+	f.Get("/.well-known/jwks.json", h.JWKS)
+	f.Get("/images/:key", h.ImageGet)
 
-	f.Post("/signup", h.SignUp)
-	f.Post("/login", h.Login)
+	f.Post("/signup", validateBody[entities.CreateUserRequest], h.SignUp)
+	f.Post("/login", validateBody[entities.LoginUserRequest], h.Login)
+	f.Post("/token/refresh", validateBody[entities.RefreshTokenRequest], h.RefreshToken)
+	f.Post("/verify-email", validateBody[entities.VerifyEmailRequest], h.VerifyEmail)
 	f.Get("/user/:id", h.GetUserDataByID)
 
-	f.Post("/cat", h.CatCreate)
-	f.Put("/cat", h.CatUpdate)
-	f.Delete("/cat/id/:id", h.CatDelete)
+	// Изменение каталога доступно только редакторам и администраторам
+	editorOnly := pkg.RequireRole(entities.RoleEditor, entities.RoleAdmin)
+	f.Post("/cat", h.authenticate, editorOnly, validateBody[entities.CreateCatRequest], h.CatCreate)
+	f.Put("/cat", h.authenticate, editorOnly, validateBody[entities.UpdateCatRequest], h.CatUpdate)
+	f.Delete("/cat/id/:id", h.authenticate, editorOnly, h.CatDelete)
 	f.Get("/cat/id/:id", h.CatGetByID)
+	f.Get("/cat/search", h.CatSearch)
 	f.Get("/cat", h.CatGetAll)
 
 	// Ручки доступные после авторизации пользователя
 	authGroup := f.Group("/auth")
-	authGroup.Use(func(c *fiber.Ctx) error {
-		return pkg.WithJWTAuth(c, config.SigningKey)
-	})
+	authGroup.Use(h.authenticate)
+
+	authGroup.Post("/logout", h.Logout)
+	authGroup.Post("/logout-all", h.LogoutAll)
 
-	authGroup.Get("/favorites", h.GetFavoriteCats)
-	authGroup.Post("/favorites/id/:id", h.AddFavoriteCat)
-	authGroup.Delete("/favorites/id/:id", h.DeleteFavoriteCat)
+	authGroup.Post("/verify-email/resend", h.ResendVerificationEmail)
+
+	// Список любимых кошек может быть доступен только с подтвержденным email
+	authGroup.Get("/favorites", h.requireVerifiedEmail, h.GetFavoriteCats)
+	authGroup.Post("/favorites/id/:id", h.requireVerifiedEmail, h.AddFavoriteCat)
+	authGroup.Delete("/favorites/id/:id", h.requireVerifiedEmail, h.DeleteFavoriteCat)
+
+	adminOnly := pkg.RequireRole(entities.RoleAdmin)
+	authGroup.Put("/admin/user/:id/role", adminOnly, validateBody[entities.UpdateUserRoleRequest], h.UpdateUserRole)
+	authGroup.Get("/admin/log-level", adminOnly, h.GetLogLevel)
+	authGroup.Put("/admin/log-level", adminOnly, validateBody[entities.LogLevel], h.SetLogLevel)
 
 	return f
 }
+
+// authenticate Проверка токена доступа
+func (h *Handler) authenticate(c *fiber.Ctx) error {
+	return pkg.WithJWTAuth(c, h.jwt, h.tokens.Denylist())
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"server/internal/entities"
+	"server/internal/log"
+	"server/internal/repository/postgres"
+	"server/internal/storage"
+	"sync"
+	"time"
+)
+
+// readinessCheck Одна проверка готовности, ошибка означает, что сервис не готов принимать запросы
+type readinessCheck func(ctx context.Context) error
+
+// Livez
+// @Tags         health
+// @Summary      Проверка жизнеспособности
+// @Description  Процесс запущен и обрабатывает запросы. Зависимости не проверяются
+// @Produce      plain
+// @Success      200 {string} string "ok"
+// @Router       /livez [get]
+func (h *Handler) Livez(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).SendString("ok")
+}
+
+// Readyz
+// @Tags         health
+// @Summary      Проверка готовности
+// @Description  Проверяет доступность бд, применение всех миграций и запись в хранилище изображений.
+// @Description  Во время плавной остановки сервиса всегда возвращает 503
+// @Produce      json
+// @Success      200 {object} entities.HealthReport "Сервис готов"
+// @Failure      503 {object} entities.HealthReport "Сервис не готов"
+// @Router       /readyz [get]
+func (h *Handler) Readyz(c *fiber.Ctx) error {
+	if h.draining.Load() {
+		report := entities.HealthReport{
+			Status: entities.HealthStatusFail,
+			Checks: map[string]entities.HealthCheck{
+				"shutdown": {Status: entities.HealthStatusFail, Error: "server is shutting down"},
+			},
+		}
+		return c.Status(fiber.StatusServiceUnavailable).JSON(report)
+	}
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), time.Duration(h.cfg.Postgres.QueryTimeout)*time.Second)
+	defer cancel()
+
+	report := h.runChecks(ctx, map[string]readinessCheck{
+		"database":   h.checkDatabase,
+		"migrations": h.checkMigrations,
+		"storage":    h.checkStorage,
+	})
+
+	status := fiber.StatusOK
+	if report.Status != entities.HealthStatusOK {
+		status = fiber.StatusServiceUnavailable
+	}
+	return c.Status(status).JSON(report)
+}
+
+// Drain Перевод сервиса в состояние остановки: /readyz начинает отвечать 503,
+// чтобы балансировщик перестал направлять новые запросы
+func (h *Handler) Drain() {
+	h.draining.Store(true)
+}
+
+// runChecks Параллельное выполнение проверок с замером времени каждой
+func (h *Handler) runChecks(ctx context.Context, checks map[string]readinessCheck) entities.HealthReport {
+	report := entities.HealthReport{
+		Status: entities.HealthStatusOK,
+		Checks: make(map[string]entities.HealthCheck, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check(ctx)
+			result := entities.HealthCheck{
+				Status:     entities.HealthStatusOK,
+				DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = entities.HealthStatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if err != nil {
+				report.Status = entities.HealthStatusFail
+				log.Ctx(ctx).Warn().Str("check", name).Err(err).Msg("readiness check failed")
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// checkDatabase Доступность бд
+func (h *Handler) checkDatabase(ctx context.Context) error {
+	return h.db.PingContext(ctx)
+}
+
+// checkMigrations Все известные приложению миграции применены
+func (h *Handler) checkMigrations(ctx context.Context) error {
+	pending, err := postgres.PendingMigrations(ctx, h.db)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending migrations, first %d", len(pending), pending[0])
+	}
+	return nil
+}
+
+// checkStorage Хранилище изображений доступно на запись
+func (h *Handler) checkStorage(ctx context.Context) error {
+	return storage.CheckWritable(ctx, h.storage)
+}
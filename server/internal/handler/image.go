@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"mime"
+	"path/filepath"
+	"regexp"
+	"server/internal/apperror"
+	"server/internal/entities"
+	"server/internal/log"
+	"server/internal/storage"
+	"strconv"
+	"strings"
+)
+
+const (
+	// imageCacheControl Имена файлов изображений - хэши содержимого, поэтому файл по ключу никогда
+	// не меняется и может кэшироваться без перепроверки
+	imageCacheControl = "public, max-age=31536000, immutable"
+	// legacyImageCacheControl Файлы, загруженные до перехода на имена по хэшу, могут быть
+	// перезаписаны под тем же именем, поэтому кэшируются ненадолго
+	legacyImageCacheControl = "public, max-age=300"
+)
+
+// hashedImageKey Ключ изображения, названного по хэшу содержимого: <sha256>.jpg
+var hashedImageKey = regexp.MustCompile(`^[0-9a-f]{64}\.jpg$`)
+
+// ImageGet
+// @Tags         image
+// @Summary      Получение изображения
+// @Description  Отдача файла изображения из хранилища. Поддерживаются условные запросы (If-None-Match)
+// @Description  и запросы диапазона байт (Range)
+// @Produce      image/jpeg
+// @Param        key            path      string  true   "Ключ изображения"
+// @Param        If-None-Match  header    string  false  "ETag ранее полученного изображения"
+// @Param        Range          header    string  false  "Диапазон байт, например bytes=0-1023"
+// @Success      200  {file}    file  "Изображение"
+// @Success      206  {file}    file  "Часть изображения"
+// @Success      304  {string}  string  "Изображение не изменилось"
+// @Failure      404  {object}  entities.Problem "Изображение не найдено"
+// @Failure      416  {object}  entities.Problem "Недопустимый диапазон"
+// @Failure      500  {object}  entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503  {object}  entities.Problem "Хранилище недоступно"
+// @Failure      504  {object}  entities.Problem "Истекло время обработки запроса"
+// @Router       /images/{key} [get]
+func (h *Handler) ImageGet(c *fiber.Ctx) error {
+	key := c.Params("key")
+	etag, cacheControl := imageCaching(key)
+
+	// Содержимое по ключу неизменно, поэтому совпадение ETag проверяется до обращения к хранилищу.
+	// "*" означает любое существующее изображение, поэтому проверяется только после его получения
+	ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch)
+	if etagMatch(ifNoneMatch, etag, false) {
+		return imageNotModified(c, etag, cacheControl)
+	}
+
+	// Тело ответа читается fasthttp уже после возврата из обработчика, когда контекст запроса
+	// отменен, поэтому поток объекта не должен зависеть от отмены контекста
+	log.Ctx(c.UserContext()).Debug().Msg("call storage.Get")
+	rc, info, err := h.storage.Get(context.WithoutCancel(c.UserContext()), key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		return apperror.Wrap(apperror.ErrNotFound, "image_not_found", "image not found", err)
+	}
+	if err != nil {
+		return err
+	}
+	if etagMatch(ifNoneMatch, etag, true) {
+		rc.Close()
+		return imageNotModified(c, etag, cacheControl)
+	}
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(key))
+	}
+	if contentType == "" {
+		contentType = fiber.MIMEOctetStream
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	if etag != "" {
+		c.Set(fiber.HeaderETag, etag)
+	}
+	c.Set(fiber.HeaderCacheControl, cacheControl)
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+
+	start, end, status := imageRange(c, etag, info.Size)
+	switch status {
+	case fiber.StatusRequestedRangeNotSatisfiable:
+		rc.Close()
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", info.Size))
+		return fiber.NewError(status, "range not satisfiable")
+	case fiber.StatusPartialContent:
+		if err := skipTo(rc, start); err != nil {
+			rc.Close()
+			return err
+		}
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, info.Size))
+
+		// fasthttp требует поток ровно заданной длины и закрывает его после отправки ответа
+		length := end - start + 1
+		body := struct {
+			io.Reader
+			io.Closer
+		}{io.LimitReader(rc, length), rc}
+		return c.Status(status).SendStream(body, int(length))
+	}
+
+	return c.Status(fiber.StatusOK).SendStream(rc, int(info.Size))
+}
+
+// imageCaching ETag и Cache-Control изображения. ETag из ключа и immutable допустимы только для
+// ключей-хэшей содержимого; у старых файлов ETag нет, а срок кэширования короткий
+func imageCaching(key string) (string, string) {
+	if !hashedImageKey.MatchString(key) {
+		return "", legacyImageCacheControl
+	}
+	return imageETag(key), imageCacheControl
+}
+
+// imageETag Строгий ETag изображения. Ключ - хэш содержимого, поэтому ETag из него
+// совпадает для одинаковых байт
+func imageETag(key string) string {
+	return strconv.Quote(strings.TrimSuffix(key, filepath.Ext(key)))
+}
+
+// imageNotModified Ответ 304 с заголовками кэширования изображения
+func imageNotModified(c *fiber.Ctx, etag, cacheControl string) error {
+	if etag != "" {
+		c.Set(fiber.HeaderETag, etag)
+	}
+	c.Set(fiber.HeaderCacheControl, cacheControl)
+	return c.SendStatus(fiber.StatusNotModified)
+}
+
+// etagMatch Проверка заголовка If-None-Match. Для If-None-Match используется слабое сравнение (RFC 9110).
+// "*" совпадает только при wildcard, то есть когда уже известно, что изображение существует.
+// Пустой etag (изображение без ETag) совпадает только с "*"
+func etagMatch(header, etag string, wildcard bool) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if (wildcard && candidate == "*") || (etag != "" && candidate == etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// imageRange Разбор заголовка Range. Возвращает границы диапазона и код ответа: 206 для одного
+// допустимого диапазона, 416 если диапазон вне файла, 200 если файл отдается целиком.
+// Некорректный заголовок, несколько диапазонов и несовпадающий If-Range игнорируются (RFC 9110)
+func imageRange(c *fiber.Ctx, etag string, size int64) (int64, int64, int) {
+	if c.Get(fiber.HeaderRange) == "" {
+		return 0, 0, fiber.StatusOK
+	}
+	if ifRange := c.Get(fiber.HeaderIfRange); ifRange != "" && ifRange != etag {
+		return 0, 0, fiber.StatusOK
+	}
+
+	ranges, err := c.Range(int(size))
+	if errors.Is(err, fiber.ErrRangeUnsatisfiable) {
+		return 0, 0, fiber.StatusRequestedRangeNotSatisfiable
+	}
+	if err != nil || ranges.Type != "bytes" || len(ranges.Ranges) != 1 {
+		return 0, 0, fiber.StatusOK
+	}
+
+	r := ranges.Ranges[0]
+	return int64(r.Start), int64(r.End), fiber.StatusPartialContent
+}
+
+// skipTo Перемотка потока на offset байт. Файлы и объекты S3 поддерживают Seek,
+// для остальных потоков байты пропускаются чтением
+func skipTo(r io.Reader, offset int64) error {
+	if seeker, ok := r.(io.Seeker); ok {
+		_, err := seeker.Seek(offset, io.SeekStart)
+		return err
+	}
+
+	_, err := io.CopyN(io.Discard, r, offset)
+	return err
+}
+
+// imageURL Преобразование ключа файла в URL для клиента
+func (h *Handler) imageURL(key string) string {
+	if key == "" {
+		return ""
+	}
+	return strings.TrimSuffix(h.cfg.Storage.PublicURL, "/") + "/" + key
+}
+
+// withImageURLs Замена ключей файлов на URL перед отправкой клиенту. В image_path котов,
+// загруженных до переноса в хранилище, записан путь на диске, из него берется имя файла
+func (h *Handler) withImageURLs(imagePath *string, images *entities.CatImages) {
+	if *imagePath != "" {
+		*imagePath = h.imageURL(filepath.Base(*imagePath))
+	}
+	images.Thumbnail = h.imageURL(images.Thumbnail)
+	images.Card = h.imageURL(images.Card)
+	images.Full = h.imageURL(images.Full)
+}
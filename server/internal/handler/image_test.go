@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"net/http/httptest"
+	"server/internal/storage"
+	"testing"
+)
+
+func TestImageGetIfNoneMatch(t *testing.T) {
+	const key = "3f2a9c0e1b7d4a56c8e9f0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5.jpg"
+	etag := imageETag(key)
+
+	st, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := []byte("jpeg")
+	if err := st.Put(context.Background(), key, bytes.NewReader(image), int64(len(image)), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Handler{storage: st}
+	app := fiber.New(fiber.Config{ErrorHandler: h.errorHandler})
+	app.Get("/images/:key", h.ImageGet)
+
+	tests := []struct {
+		name        string
+		key         string
+		ifNoneMatch string
+		want        int
+	}{
+		{name: "no header", key: key, want: fiber.StatusOK},
+		{name: "other etag", key: key, ifNoneMatch: `"other"`, want: fiber.StatusOK},
+		{name: "same etag", key: key, ifNoneMatch: etag, want: fiber.StatusNotModified},
+		{name: "weak etag in list", key: key, ifNoneMatch: `"other", W/` + etag, want: fiber.StatusNotModified},
+		{name: "any existing", key: key, ifNoneMatch: "*", want: fiber.StatusNotModified},
+		{name: "missing", key: "0c1d2e3f405162738495a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e7f8091.jpg", want: fiber.StatusNotFound},
+		{name: "any missing", key: "0c1d2e3f405162738495a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e7f8091.jpg", ifNoneMatch: "*", want: fiber.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/images/"+tt.key, nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set(fiber.HeaderIfNoneMatch, tt.ifNoneMatch)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageGetCaching(t *testing.T) {
+	const hashedKey = "3f2a9c0e1b7d4a56c8e9f0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5.jpg"
+	const legacyKey = "cat.jpg"
+
+	st, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := []byte("jpeg")
+	for _, key := range []string{hashedKey, legacyKey} {
+		if err := st.Put(context.Background(), key, bytes.NewReader(image), int64(len(image)), "image/jpeg"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &Handler{storage: st}
+	app := fiber.New(fiber.Config{ErrorHandler: h.errorHandler})
+	app.Get("/images/:key", h.ImageGet)
+
+	tests := []struct {
+		name             string
+		key              string
+		ifNoneMatch      string
+		wantStatus       int
+		wantETag         string
+		wantCacheControl string
+	}{
+		{name: "hashed", key: hashedKey, wantStatus: fiber.StatusOK, wantETag: imageETag(hashedKey),
+			wantCacheControl: imageCacheControl},
+		{name: "legacy", key: legacyKey, wantStatus: fiber.StatusOK, wantCacheControl: legacyImageCacheControl},
+		{name: "legacy with key etag", key: legacyKey, ifNoneMatch: `"cat"`, wantStatus: fiber.StatusOK,
+			wantCacheControl: legacyImageCacheControl},
+		{name: "legacy any existing", key: legacyKey, ifNoneMatch: "*", wantStatus: fiber.StatusNotModified,
+			wantCacheControl: legacyImageCacheControl},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/images/"+tt.key, nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set(fiber.HeaderIfNoneMatch, tt.ifNoneMatch)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get(fiber.HeaderETag); got != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+			}
+			if got := resp.Header.Get(fiber.HeaderCacheControl); got != tt.wantCacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCacheControl)
+			}
+		})
+	}
+}
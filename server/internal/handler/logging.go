@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"server/internal/entities"
+	"server/internal/log"
+)
+
+// GetLogLevel
+// @Tags         admin
+// @Summary      Текущий уровень логирования
+// @Description  Уровень логирования экземпляра бэкенда, обработавшего запрос
+// @Produce      json
+// @Success      200  {object}  entities.LogLevel  "Уровень логирования"
+// @Failure      401  {object}  entities.Problem  "Пользователь не авторизован"
+// @Failure      403  {object}  entities.Problem  "Недостаточно прав"
+// @Router       /auth/admin/log-level [get]
+// @Security ApiKeyAuth
+func (h *Handler) GetLogLevel(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(entities.LogLevel{Level: log.Level()})
+}
+
+// SetLogLevel
+// @Tags         admin
+// @Summary      Изменение уровня логирования
+// @Description  Меняет уровень логирования без перезапуска. Действует только на экземпляр бэкенда,
+// @Description  обработавший запрос, и сбрасывается на значение из конфигурации при перезапуске
+// @Accept       json
+// @Produce      json
+// @Param        data body entities.LogLevel true "Новый уровень"
+// @Success      200  {object}  entities.LogLevel  "Уровень изменен"
+// @Failure      400  {object}  entities.Problem  "Некорректные данные"
+// @Failure      401  {object}  entities.Problem  "Пользователь не авторизован"
+// @Failure      403  {object}  entities.Problem  "Недостаточно прав"
+// @Failure      422  {object}  entities.Problem  "Данные не прошли проверку"
+// @Router       /auth/admin/log-level [put]
+// @Security ApiKeyAuth
+func (h *Handler) SetLogLevel(c *fiber.Ctx) error {
+	req := requestBody[entities.LogLevel](c)
+
+	previous := log.Level()
+	if err := log.SetLevel(req.Level); err != nil {
+		return err
+	}
+	// Warn, чтобы смена уровня попала в лог при любом новом уровне, кроме error
+	log.Ctx(c.UserContext()).Warn().Str("from", previous).Str("to", req.Level).Msg("log level changed")
+
+	return c.Status(fiber.StatusOK).JSON(entities.LogLevel{Level: log.Level()})
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"server/internal/apperror"
+	"server/internal/log"
+	"server/internal/metrics"
+	"server/internal/ratelimit"
+)
+
+// checkAuthLimits Проверка лимитов попыток входа и регистрации до проверки пароля. После
+// нескольких неудачных входов ответ задерживается на сервере. Если хранилище счетчиков
+// недоступно, попытка отклоняется с 503, а при rate_limit.fail_open пропускается без проверки
+func (h *Handler) checkAuthLimits(c *fiber.Ctx, email string) error {
+	log.Ctx(c.UserContext()).Debug().Msg("call ratelimit.Limiter.Allow")
+	delay, err := h.limiter.Allow(c.UserContext(), c.IP(), email)
+
+	var exceeded *ratelimit.ExceededError
+	if errors.As(err, &exceeded) {
+		metrics.RateLimited.WithLabelValues(exceeded.Reason).Inc()
+		return rateLimited(exceeded)
+	}
+	if err != nil {
+		if !h.cfg.RateLimit.FailOpen {
+			return apperror.Wrap(apperror.ErrUnavailable, "rate_limiter_unavailable",
+				"login is temporarily unavailable, retry later", err)
+		}
+		log.Ctx(c.UserContext()).Error().Err(err).Msg("rate limiter unavailable, attempt allowed")
+		return nil
+	}
+
+	if delay > 0 {
+		metrics.RateLimited.WithLabelValues(ratelimit.ReasonDelay).Inc()
+		log.Ctx(c.UserContext()).Debug().Dur("delay", delay).Msg("attempt delayed after failed logins")
+		return ratelimit.Wait(c.UserContext(), delay)
+	}
+	return nil
+}
+
+// recordLogin Учет исхода входа: неудачи ведут к задержкам и блокировке, успешный вход их сбрасывает
+func (h *Handler) recordLogin(c *fiber.Ctx, email string, failed bool) {
+	if !failed {
+		if err := h.limiter.Success(c.UserContext(), email); err != nil {
+			log.Ctx(c.UserContext()).Error().Err(err).Msg("failed to reset login failures")
+		}
+		return
+	}
+
+	locked, err := h.limiter.Failure(c.UserContext(), email)
+	if err != nil {
+		log.Ctx(c.UserContext()).Error().Err(err).Msg("failed to record login failure")
+	}
+	if locked {
+		metrics.AccountLockouts.Inc()
+		log.Ctx(c.UserContext()).Warn().Str("ip", c.IP()).Msg("login locked after repeated failures")
+	}
+}
+
+// rateLimited Ошибка 429 по причине отказа. Retry-After выставляет errorHandler
+func rateLimited(exceeded *ratelimit.ExceededError) error {
+	switch exceeded.Reason {
+	case ratelimit.ReasonLocked:
+		return apperror.Wrap(apperror.ErrTooManyRequests, "account_locked",
+			"login temporarily locked after repeated failed attempts", exceeded)
+	}
+	return apperror.Wrap(apperror.ErrTooManyRequests, "too_many_requests", "too many attempts, retry later", exceeded)
+}
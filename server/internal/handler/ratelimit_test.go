@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"net/http/httptest"
+	"server/internal/config"
+	"server/internal/entities"
+	"server/internal/ratelimit"
+	"testing"
+	"time"
+)
+
+// unavailableStore Хранилище счетчиков, которое всегда недоступно
+type unavailableStore struct {
+	ratelimit.Store
+}
+
+func (unavailableStore) LockedUntil(context.Context, string, time.Time) (time.Time, error) {
+	return time.Time{}, errors.New("connection refused")
+}
+
+// authLimitsApp Приложение с одной ручкой, проверяющей лимиты попыток для petrov@mail.ru
+func authLimitsApp(store ratelimit.Store, cfg config.RateLimitConfig, timeout time.Duration) *fiber.App {
+	h := &Handler{
+		cfg:     &config.Config{RateLimit: cfg},
+		limiter: ratelimit.NewLimiter(store, cfg),
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: h.appErrorHandler})
+	app.Use(h.handleErrors)
+	app.Post("/login", func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		if err := h.checkAuthLimits(c, "petrov@mail.ru"); err != nil {
+			return err
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	return app
+}
+
+func authLimitsConfig() config.RateLimitConfig {
+	return config.RateLimitConfig{
+		Enabled:       true,
+		IP:            config.RateLimitWindow{Limit: 100, Window: 60},
+		Email:         config.RateLimitWindow{Limit: 100, Window: 60},
+		FailureWindow: 900,
+		DelayAfter:    1,
+		DelayBase:     1,
+		DelayMax:      1,
+	}
+}
+
+func TestCheckAuthLimitsStoreUnavailable(t *testing.T) {
+	tests := []struct {
+		name       string
+		failOpen   bool
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "fail closed", wantStatus: fiber.StatusServiceUnavailable, wantCode: "rate_limiter_unavailable"},
+		{name: "fail open", failOpen: true, wantStatus: fiber.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := authLimitsConfig()
+			cfg.FailOpen = tt.failOpen
+			app := authLimitsApp(unavailableStore{}, cfg, time.Second)
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/login", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var problem entities.Problem
+			if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", problem.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCheckAuthLimitsDelay(t *testing.T) {
+	cfg := authLimitsConfig()
+	store := ratelimit.NewMemory()
+	limiter := ratelimit.NewLimiter(store, cfg)
+	for i := 0; i < 2; i++ {
+		if _, err := limiter.Failure(context.Background(), "petrov@mail.ru"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Ответ задерживается на сервере, а не отклоняется: задержка длиннее времени запроса
+	// прерывается по его истечении
+	app := authLimitsApp(store, cfg, 50*time.Millisecond)
+	start := time.Now()
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/login", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != fiber.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusGatewayTimeout)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("answered after %s, want delayed until request timeout", elapsed)
+	}
+
+	// Если времени запроса хватает, попытка принимается после задержки
+	app = authLimitsApp(store, cfg, 5*time.Second)
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodPost, "/login", nil), 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusNoContent)
+	}
+}
@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"time"
+)
+
+// requestTimeout Middleware, ограничивающий время обработки запроса. Контекст с дедлайном
+// передается во все обращения к бд и хранилищу через c.UserContext(). fasthttp не сообщает
+// об обрыве соединения клиентом, поэтому незавершенные запросы ограничиваются только дедлайном
+func (h *Handler) requestTimeout(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), time.Duration(h.cfg.App.RequestTimeout)*time.Second)
+	defer cancel()
+
+	c.SetUserContext(ctx)
+	return c.Next()
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"server/internal/apperror"
+	"server/internal/entities"
+	"server/internal/log"
+	"server/pkg"
+	"time"
+)
+
+// RefreshToken
+// @Tags         user
+// @Summary      Обновление токенов
+// @Description  Обменивает рефреш токен на новую пару токенов. Использованный рефреш токен становится недействительным, а его повторное предъявление отзывает всю цепочку токенов
+// @Accept       json
+// @Produce      json
+// @Param        data body entities.RefreshTokenRequest true "Рефреш токен"
+// @Success      200 {object} entities.RefreshTokenResponse "Токены обновлены"
+// @Failure      400 {object} entities.Problem "Некорректные данные"
+// @Failure      401 {object} entities.Problem "Рефреш токен недействителен"
+// @Failure      422 {object} entities.Problem "Данные не прошли проверку"
+// @Failure      500 {object} entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503 {object} entities.Problem "База данных недоступна"
+// @Failure      504 {object} entities.Problem "Истекло время обработки запроса"
+// @Router       /token/refresh [post]
+func (h *Handler) RefreshToken(c *fiber.Ctx) error {
+	req := requestBody[entities.RefreshTokenRequest](c)
+
+	log.Ctx(c.UserContext()).Debug().Msg("call service.TokenService.Refresh")
+	accessToken, refreshToken, err := h.tokens.Refresh(c.UserContext(), req.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	res := entities.RefreshTokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+// Logout
+// @Tags         user
+// @Summary      Выход из текущей сессии
+// @Description  Отзывает текущий токен доступа и все рефреш токены его сессии
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} entities.Message "Выход выполнен"
+// @Failure      401 {object} entities.Problem "Пользователь не авторизован"
+// @Failure      500 {object} entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503 {object} entities.Problem "База данных недоступна"
+// @Failure      504 {object} entities.Problem "Истекло время обработки запроса"
+// @Router       /auth/logout [post]
+// @Security ApiKeyAuth
+func (h *Handler) Logout(c *fiber.Ctx) error {
+	token, ok := c.Locals("token").(*pkg.AccessToken)
+	if !ok {
+		return apperror.Unauthorized("missing_token", "missing auth token")
+	}
+
+	log.Ctx(c.UserContext()).Debug().Msg("call service.TokenService.Logout")
+	if err := h.tokens.Logout(c.UserContext(), token); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "success"})
+}
+
+// LogoutAll
+// @Tags         user
+// @Summary      Выход из всех сессий
+// @Description  Отзывает все выданные пользователю токены доступа и рефреш токены
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} entities.Message "Выход выполнен"
+// @Failure      401 {object} entities.Problem "Пользователь не авторизован"
+// @Failure      500 {object} entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503 {object} entities.Problem "База данных недоступна"
+// @Failure      504 {object} entities.Problem "Истекло время обработки запроса"
+// @Router       /auth/logout-all [post]
+// @Security ApiKeyAuth
+func (h *Handler) LogoutAll(c *fiber.Ctx) error {
+	id, ok := c.Locals("id").(int)
+	if !ok {
+		return apperror.Unauthorized("missing_token", "missing auth token")
+	}
+
+	log.Ctx(c.UserContext()).Debug().Msg("call service.TokenService.LogoutAll")
+	if err := h.tokens.LogoutAll(c.UserContext(), id); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "success"})
+}
+
+// RunDenylistSync Периодическая загрузка отозванных токенов из бд в кэш, чтобы отзывы,
+// сделанные другими экземплярами бэкенда, вступали в силу. Блокируется до отмены ctx
+func (h *Handler) RunDenylistSync(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(h.cfg.App.RevocationSyncInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		h.syncDenylist(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncDenylist Одна итерация синхронизации кэша отозванных токенов. Заодно удаляются
+// истекшие записи отозванных токенов
+func (h *Handler) syncDenylist(ctx context.Context) {
+	if err := h.tokens.CleanupRevoked(ctx); err != nil {
+		h.logger.Error().Err(err).Msg("failed to clean up revoked access tokens")
+	}
+
+	if err := h.tokens.SyncDenylist(ctx); err != nil {
+		h.logger.Error().Err(err).Msg("failed to load revoked access tokens")
+	}
+}
+
+// JWKS
+// @Tags         user
+// @Summary      Открытые ключи подписи токенов
+// @Description  Набор открытых ключей в формате JWKS для проверки токенов другими сервисами. При подписи HS256 набор пуст
+// @Produce      json
+// @Success      200 {object} pkg.JWKSet "Набор ключей"
+// @Router       /.well-known/jwks.json [get]
+func (h *Handler) JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(h.jwt.Keys.JWKS())
+}
@@ -1,102 +1,62 @@
 package handler
 
 import (
+	"errors"
 	"github.com/gofiber/fiber/v2"
-	"server/internal/config"
 	"server/internal/entities"
 	"server/internal/log"
-	"server/internal/repository/postgres"
-	"server/pkg"
-	"server/util"
+	"server/internal/metrics"
+	"server/internal/service"
 	"strconv"
 )
 
 // SignUp
 // @Tags         user
 // @Summary      Регистрация пользователя
-// @Description  Создает нового пользователя и выдает ему токен доступа
+// @Description  Создает нового пользователя с неподтвержденным email и выдает ему токен доступа и рефреш токен. На email отправляется ссылка для подтверждения адреса через POST /verify-email
 // @Accept       json
 // @Produce      json
 // @Param        data body entities.CreateUserRequest true "Данные для регистрации"
 // @Success      200 {object} entities.CreateUserResponse "Регистрация успешна"
-// @Failure      400 {object} entities.ErrorResponse "Пользователь уже существует или данные неверны"
-// @Failure      500 {object} entities.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure      400 {object} entities.Problem "Некорректные данные"
+// @Failure      409 {object} entities.Problem "Пользователь уже существует"
+// @Failure      422 {object} entities.Problem "Данные не прошли проверку"
+// @Failure      429 {object} entities.Problem "Слишком много попыток, повторить через Retry-After секунд"
+// @Failure      500 {object} entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503 {object} entities.Problem "База данных или хранилище счетчиков попыток недоступны"
+// @Failure      504 {object} entities.Problem "Истекло время обработки запроса"
 // @Router       /signup [post]
 func (h *Handler) SignUp(c *fiber.Ctx) error {
-	var u entities.CreateUserRequest
-	if err := c.BodyParser(&u); err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusBadRequest})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
-	}
+	u := requestBody[entities.CreateUserRequest](c)
 
-	h.logger.Debug().Msg("call postgres.DBUserExists")
-	exists, err := postgres.DBUserExists(h.db, u.Email)
-	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-	}
-	if exists {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusBadRequest})
-		logEvent.Msg("user already exists")
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "user already exists"})
+	if err := h.checkAuthLimits(c, u.Email); err != nil {
+		return err
 	}
 
-	hashedPassword, err := util.HashPassword(u.Password)
+	log.Ctx(c.UserContext()).Debug().Msg("call service.UserService.SignUp")
+	user, err := h.users.SignUp(c.UserContext(), u)
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
-	user := &entities.User{
-		Email:    u.Email,
-		Password: hashedPassword,
-		Name:     u.Name,
-		Surname:  u.Surname,
+	// Регистрация не отменяется из-за почты: письмо можно запросить повторно
+	log.Ctx(c.UserContext()).Debug().Msg("call service.VerificationService.Send")
+	if err := h.verifications.Send(c.UserContext(), user); err != nil {
+		log.Ctx(c.UserContext()).Error().Err(err).Msg("failed to send verification email")
 	}
 
-	h.logger.Debug().Msg("call postgres.DBUserCreate")
-	r, err := postgres.DBUserCreate(h.db, user)
-	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-	}
-
-	TokenExpiration, err := strconv.Atoi(config.TokenExpiration)
-	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg("wrong data")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "wrong data"})
-	}
-	h.logger.Debug().Msg("call pkg.GenerateAccessToken")
-	accessToken, err := pkg.GenerateAccessToken(user.ID, TokenExpiration,
-		config.SigningKey)
+	log.Ctx(c.UserContext()).Debug().Msg("call service.TokenService.Issue")
+	accessToken, refreshToken, err := h.tokens.Issue(c.UserContext(), user)
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
-	h.logger.Debug().Msg("call pkg.GenerateRefreshToken")
-
 	res := &entities.CreateUserResponse{
-		ID:          r.ID,
-		AccessToken: accessToken,
+		ID:           user.ID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 	}
 
-	logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Info", Method: c.Method(),
-		Url: c.OriginalURL(), Status: fiber.StatusOK})
-	logEvent.Msg("success")
 	return c.Status(fiber.StatusOK).JSON(res)
 
 }
@@ -104,67 +64,48 @@ func (h *Handler) SignUp(c *fiber.Ctx) error {
 // Login
 // @Tags         user
 // @Summary      Вход пользователя
-// @Description  Аутентификация пользователя с возвращением токена доступа
+// @Description  Аутентификация пользователя с возвращением токена доступа и рефреш токена. Попытки ограничены по IP и email, после нескольких неудач ответ на следующие попытки задерживается на сервере, а после многих неудач вход на email временно блокируется
 // @Accept       json
 // @Produce      json
 // @Param        data body entities.LoginUserRequest true "Данные для входа"
 // @Success      200 {object} entities.LoginUserResponse "Успешный вход"
-// @Failure      400 {object} entities.ErrorResponse "Неверный логин или пароль"
-// @Failure      500 {object} entities.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure      400 {object} entities.Problem "Некорректные данные"
+// @Failure      401 {object} entities.Problem "Неверный логин или пароль"
+// @Failure      422 {object} entities.Problem "Данные не прошли проверку"
+// @Failure      429 {object} entities.Problem "Слишком много попыток или вход временно заблокирован, повторить через Retry-After секунд"
+// @Failure      500 {object} entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503 {object} entities.Problem "База данных или хранилище счетчиков попыток недоступны"
+// @Failure      504 {object} entities.Problem "Истекло время обработки запроса"
 // @Router       /login [post]
 func (h *Handler) Login(c *fiber.Ctx) error {
-	var user entities.LoginUserRequest
-	if err := c.BodyParser(&user); err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusBadRequest})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err})
+	user := requestBody[entities.LoginUserRequest](c)
+
+	if err := h.checkAuthLimits(c, user.Email); err != nil {
+		return err
 	}
 
-	h.logger.Debug().Msg("call postgres.DBUserGetByLogin")
-	u, err := postgres.DBUserGetByEmail(h.db, user.Email)
-	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusBadRequest})
-		logEvent.Msg("wrong data")
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "wrong data"})
+	log.Ctx(c.UserContext()).Debug().Msg("call service.UserService.Authenticate")
+	u, err := h.users.Authenticate(c.UserContext(), user.Email, user.Password)
+	metrics.Logins.WithLabelValues(metrics.Result(err)).Inc()
+	if err == nil || errors.Is(err, service.ErrInvalidCredentials) {
+		h.recordLogin(c, user.Email, err != nil)
 	}
-	h.logger.Debug().Msg("call util.CheckPassword")
-	err = util.CheckPassword(user.Password, u.Password)
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusBadRequest})
-		logEvent.Msg("wrong data")
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "wrong data"})
+		return err
 	}
 
-	TokenExpiration, err := strconv.Atoi(config.TokenExpiration)
+	log.Ctx(c.UserContext()).Debug().Msg("call service.TokenService.Issue")
+	accessToken, refreshToken, err := h.tokens.Issue(c.UserContext(), u)
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusBadRequest})
-		logEvent.Msg("wrong data")
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "wrong data"})
+		return err
 	}
-	h.logger.Debug().Msg("call pkg.GenerateAccessToken")
-	accessToken, err := pkg.GenerateAccessToken(u.ID, TokenExpiration,
-		config.SigningKey)
-	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-	}
-
-	h.logger.Debug().Msg("call pkg.GenerateRefreshToken")
 
 	res := entities.LoginUserResponse{
-		AccessToken: accessToken,
-		ID:          u.ID,
+		AccessToken:  accessToken,
+		ID:           u.ID,
+		RefreshToken: refreshToken,
 	}
 
-	logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Info", Method: c.Method(),
-		Url: c.OriginalURL(), Status: fiber.StatusOK})
-	logEvent.Msg("success")
 	return c.Status(fiber.StatusOK).JSON(res)
 }
 
@@ -176,45 +117,66 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        id   path      int  true  "ID пользователя"
 // @Success      200  {object}  entities.UserData  "Пользовательские данные получены"
-// @Failure      400  {object}  entities.ErrorResponse  "Неверный формат ID"
-// @Failure      500  {object}  entities.ErrorResponse  "Внутренняя ошибка сервера"
+// @Failure      400  {object}  entities.Problem  "Неверный формат ID"
+// @Failure      404  {object}  entities.Problem  "Пользователь не найден"
+// @Failure      500  {object}  entities.Problem  "Внутренняя ошибка сервера"
+// @Failure      503  {object}  entities.Problem  "База данных недоступна"
+// @Failure      504  {object}  entities.Problem  "Истекло время обработки запроса"
 // @Router       /user/{id} [get]
 func (h *Handler) GetUserDataByID(c *fiber.Ctx) error {
 	idStr := c.Params("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return invalidParam("id", err)
 	}
 
-	h.logger.Debug().Msg("call postgres.DBUserExistsID")
-	exists, err := postgres.DBUserExistsID(h.db, int64(id))
+	log.Ctx(c.UserContext()).Debug().Msg("call service.UserService.GetData")
+	user, err := h.users.GetData(c.UserContext(), id)
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
-	if !exists {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusBadRequest})
-		logEvent.Msg("user not exists")
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "user not exists"})
+
+	return c.Status(fiber.StatusOK).JSON(user)
+}
+
+// UpdateUserRole
+// @Tags         user
+// @Summary      Изменение роли пользователя
+// @Description  Назначает пользователю роль user, editor или admin. Выданные пользователю токены отзываются, чтобы новая роль вступила в силу
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "ID пользователя"
+// @Param        data body entities.UpdateUserRoleRequest true "Новая роль"
+// @Success      200  {object}  entities.Message  "Роль изменена"
+// @Failure      400  {object}  entities.Problem  "Некорректные данные"
+// @Failure      401  {object}  entities.Problem  "Пользователь не авторизован"
+// @Failure      403  {object}  entities.Problem  "Недостаточно прав"
+// @Failure      404  {object}  entities.Problem  "Пользователь не найден"
+// @Failure      422  {object}  entities.Problem  "Данные не прошли проверку"
+// @Failure      500  {object}  entities.Problem  "Внутренняя ошибка сервера"
+// @Failure      503  {object}  entities.Problem  "База данных недоступна"
+// @Failure      504  {object}  entities.Problem  "Истекло время обработки запроса"
+// @Router       /auth/admin/user/{id}/role [put]
+// @Security ApiKeyAuth
+func (h *Handler) UpdateUserRole(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return invalidParam("id", err)
 	}
 
-	h.logger.Debug().Msg("call postgres.DBUserGetByID")
-	user, err := postgres.DBUserDataGetById(h.db, int64(id))
+	req := requestBody[entities.UpdateUserRoleRequest](c)
+
+	log.Ctx(c.UserContext()).Debug().Msg("call service.UserService.UpdateRole")
+	err = h.users.UpdateRole(c.UserContext(), id, req.Role)
 	if err != nil {
-		logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Error", Method: c.Method(),
-			Url: c.OriginalURL(), Status: fiber.StatusInternalServerError})
-		logEvent.Msg(err.Error())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
-	logEvent := log.CreateLog(h.logger, log.LogsField{Level: "Info", Method: c.Method(),
-		Url: c.OriginalURL(), Status: fiber.StatusOK})
-	logEvent.Msg("success")
-	return c.Status(fiber.StatusOK).JSON(user)
+	// Роль хранится в токене, поэтому старые токены с прежней ролью отзываются
+	log.Ctx(c.UserContext()).Debug().Msg("call service.TokenService.RevokeAccess")
+	if err := h.tokens.RevokeAccess(c.UserContext(), id); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "success"})
 }
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"server/internal/validation"
+)
+
+// bodyKey Ключ c.Locals, под которым validateBody сохраняет проверенное тело запроса
+const bodyKey = "body"
+
+// validateBody Middleware разбора тела запроса (JSON или форма) в T и проверки по тегам validate.
+// Ручка вызывается только для корректного тела и получает его через requestBody
+func validateBody[T any](c *fiber.Ctx) error {
+	body := new(T)
+	if err := c.BodyParser(body); err != nil {
+		return invalidBody(err)
+	}
+	if err := validation.Struct(body); err != nil {
+		return err
+	}
+
+	c.Locals(bodyKey, body)
+	return c.Next()
+}
+
+// requestBody Тело запроса, проверенное validateBody[T]
+func requestBody[T any](c *fiber.Ctx) *T {
+	return c.Locals(bodyKey).(*T)
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"server/internal/apperror"
+	"server/internal/entities"
+	"server/internal/log"
+	"time"
+)
+
+// verificationCleanupInterval Период удаления истекших и использованных токенов подтверждения email
+const verificationCleanupInterval = time.Hour
+
+// VerifyEmail
+// @Tags         user
+// @Summary      Подтверждение email
+// @Description  Подтверждает email пользователя по токену из письма, отправленного при регистрации. Токен одноразовый и действует ограниченное время
+// @Accept       json
+// @Produce      json
+// @Param        data body entities.VerifyEmailRequest true "Токен из ссылки в письме"
+// @Success      200 {object} entities.Message "Email подтвержден"
+// @Failure      400 {object} entities.Problem "Токен недействителен, уже использован или истек"
+// @Failure      422 {object} entities.Problem "Данные не прошли проверку"
+// @Failure      500 {object} entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503 {object} entities.Problem "База данных недоступна"
+// @Failure      504 {object} entities.Problem "Истекло время обработки запроса"
+// @Router       /verify-email [post]
+func (h *Handler) VerifyEmail(c *fiber.Ctx) error {
+	req := requestBody[entities.VerifyEmailRequest](c)
+
+	log.Ctx(c.UserContext()).Debug().Msg("call service.VerificationService.Verify")
+	userID, err := h.verifications.Verify(c.UserContext(), req.Token)
+	if err != nil {
+		return err
+	}
+	log.WithUserID(c, userID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "success"})
+}
+
+// ResendVerificationEmail
+// @Tags         user
+// @Summary      Повторная отправка письма для подтверждения email
+// @Description  Отправляет новую ссылку подтверждения email. Ранее отправленные ссылки действуют до истечения срока
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} entities.Message "Письмо отправлено"
+// @Failure      401 {object} entities.Problem "Пользователь не авторизован"
+// @Failure      409 {object} entities.Problem "Email уже подтвержден"
+// @Failure      429 {object} entities.Problem "Слишком много попыток, повторить через Retry-After секунд"
+// @Failure      500 {object} entities.Problem "Внутренняя ошибка сервера"
+// @Failure      503 {object} entities.Problem "База данных, хранилище счетчиков попыток или почтовый сервер недоступны"
+// @Failure      504 {object} entities.Problem "Истекло время обработки запроса"
+// @Router       /auth/verify-email/resend [post]
+// @Security ApiKeyAuth
+func (h *Handler) ResendVerificationEmail(c *fiber.Ctx) error {
+	id, ok := c.Locals("id").(int)
+	if !ok {
+		return apperror.Unauthorized("missing_token", "missing auth token")
+	}
+
+	log.Ctx(c.UserContext()).Debug().Msg("call service.UserService.GetByID")
+	user, err := h.users.GetByID(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return apperror.Conflict("email_already_verified", "email already verified")
+	}
+
+	// Письма отправляются на адрес пользователя, поэтому повторы ограничены как попытки входа
+	if err := h.checkAuthLimits(c, user.Email); err != nil {
+		return err
+	}
+
+	log.Ctx(c.UserContext()).Debug().Msg("call service.VerificationService.Send")
+	if err := h.verifications.Send(c.UserContext(), user); err != nil {
+		return apperror.Wrap(apperror.ErrUnavailable, "mail_unavailable", "failed to send verification email", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "success"})
+}
+
+// RunVerificationCleanup Периодическое удаление истекших и использованных токенов подтверждения
+// email из бд. Блокируется до отмены ctx
+func (h *Handler) RunVerificationCleanup(ctx context.Context) {
+	ticker := time.NewTicker(verificationCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		h.cleanupVerificationTokens(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// cleanupVerificationTokens Одна итерация удаления токенов подтверждения email
+func (h *Handler) cleanupVerificationTokens(ctx context.Context) {
+	if err := h.verifications.Cleanup(ctx); err != nil {
+		h.logger.Error().Err(err).Msg("failed to clean up email verification tokens")
+	}
+}
+
+// requireVerifiedEmail Middleware, пропускающий только пользователей с подтвержденным email, если
+// это включено в app.require_verified_email. Должен стоять после authenticate
+func (h *Handler) requireVerifiedEmail(c *fiber.Ctx) error {
+	if !h.cfg.App.RequireVerifiedEmail {
+		return c.Next()
+	}
+
+	id, ok := c.Locals("id").(int)
+	if !ok {
+		return apperror.Unauthorized("missing_token", "missing auth token")
+	}
+
+	log.Ctx(c.UserContext()).Debug().Msg("call service.UserService.GetByID")
+	user, err := h.users.GetByID(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		return apperror.Forbidden("email_not_verified", "email address is not verified")
+	}
+
+	return c.Next()
+}
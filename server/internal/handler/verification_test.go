@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+	"net/http"
+	"net/http/httptest"
+	"server/internal/config"
+	"server/internal/entities"
+	"server/internal/ratelimit"
+	"server/internal/repository"
+	"server/internal/repository/memory"
+	"server/internal/service"
+	"server/pkg"
+	"server/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verificationTest Приложение поверх хранилищ в памяти с двумя пользователями без подтвержденного email
+type verificationTest struct {
+	app   *fiber.App
+	repos repository.Repositories
+	jwt   *pkg.JWT
+	user  *entities.User
+	other *entities.User
+}
+
+func newVerificationTest(t *testing.T, requireVerified bool) *verificationTest {
+	t.Helper()
+	ctx := context.Background()
+
+	repos := memory.NewRepositories()
+	user, err := repos.Users.Create(ctx, &entities.User{Email: "petrov@mail.ru", Name: "Петр", Surname: "Петров", Role: entities.RoleUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := repos.Users.Create(ctx, &entities.User{Email: "ivanov@mail.ru", Name: "Иван", Surname: "Иванов", Role: entities.RoleUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwt, err := pkg.NewJWT("kotiki", []string{"kotiki"}, "", "", "test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		App: config.AppConfig{
+			TokenExpiration:        1,
+			RefreshTokenExpiration: 24,
+			RequestTimeout:         5,
+			RequireVerifiedEmail:   requireVerified,
+		},
+		Mail: config.MailConfig{VerificationExpiration: 24},
+	}
+	logger := zerolog.Nop()
+	// Письма не отправляются: токены подтверждения сохраняются в тестах напрямую
+	services := service.New(repos, nil, jwt, nil, cfg)
+	h := NewHandler(nil, &logger, cfg, jwt, nil, ratelimit.NewLimiter(ratelimit.NewMemory(), cfg.RateLimit), services)
+
+	return &verificationTest{app: h.Router(), repos: repos, jwt: jwt, user: user, other: other}
+}
+
+// verificationToken Токен подтверждения email пользователя userID, хэш которого сохранен
+// для пользователя storedFor с окончанием действия expiresAt
+func (vt *verificationTest) verificationToken(t *testing.T, userID, storedFor int, expiresAt time.Time) string {
+	t.Helper()
+
+	token, err := pkg.GenerateEmailVerificationToken(userID, 24, vt.jwt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vt.repos.EmailVerifications.Create(context.Background(), util.HashToken(token), storedFor, expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// verify Запрос подтверждения email, возвращает код ответа и код ошибки
+func (vt *verificationTest) verify(t *testing.T, token string) (int, string) {
+	t.Helper()
+
+	body, _ := json.Marshal(entities.VerifyEmailRequest{Token: token})
+	req := httptest.NewRequest(fiber.MethodPost, "/verify-email", strings.NewReader(string(body)))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return vt.do(t, req)
+}
+
+// favorites Запрос списка любимых кошек пользователя, возвращает код ответа и код ошибки
+func (vt *verificationTest) favorites(t *testing.T, user *entities.User) (int, string) {
+	t.Helper()
+
+	accessToken, err := pkg.GenerateAccessToken(user.ID, user.Role, 1, "", vt.jwt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(fiber.MethodGet, "/auth/favorites", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+accessToken)
+	return vt.do(t, req)
+}
+
+func (vt *verificationTest) do(t *testing.T, req *http.Request) (int, string) {
+	t.Helper()
+
+	resp, err := vt.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < fiber.StatusBadRequest {
+		return resp.StatusCode, ""
+	}
+	var problem entities.Problem
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, problem.Code
+}
+
+// emailVerified Подтвержден ли email пользователя
+func (vt *verificationTest) emailVerified(t *testing.T, user *entities.User) bool {
+	t.Helper()
+
+	u, err := vt.repos.Users.GetByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.EmailVerifiedAt != nil
+}
+
+func TestVerifyEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		// token токен для проверки, перед ним может быть использован другой токен
+		token      func(t *testing.T, vt *verificationTest) string
+		wantStatus int
+		wantCode   string
+		// wantVerified подтвержден ли email после запроса у user и other
+		wantVerified      bool
+		wantOtherVerified bool
+	}{
+		{
+			name: "valid",
+			token: func(t *testing.T, vt *verificationTest) string {
+				return vt.verificationToken(t, vt.user.ID, vt.user.ID, time.Now().Add(time.Hour))
+			},
+			wantStatus:   fiber.StatusOK,
+			wantVerified: true,
+		},
+		{
+			name: "used",
+			token: func(t *testing.T, vt *verificationTest) string {
+				token := vt.verificationToken(t, vt.user.ID, vt.user.ID, time.Now().Add(time.Hour))
+				if status, code := vt.verify(t, token); status != fiber.StatusOK {
+					t.Fatalf("first verify: status = %d, code = %q", status, code)
+				}
+				return token
+			},
+			wantStatus:   fiber.StatusBadRequest,
+			wantCode:     "invalid_verification_token",
+			wantVerified: true,
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T, vt *verificationTest) string {
+				return vt.verificationToken(t, vt.user.ID, vt.user.ID, time.Now().Add(-time.Minute))
+			},
+			wantStatus: fiber.StatusBadRequest,
+			wantCode:   "invalid_verification_token",
+		},
+		{
+			name: "issued to another user",
+			token: func(t *testing.T, vt *verificationTest) string {
+				return vt.verificationToken(t, vt.user.ID, vt.other.ID, time.Now().Add(time.Hour))
+			},
+			wantStatus: fiber.StatusBadRequest,
+			wantCode:   "invalid_verification_token",
+		},
+		{
+			name: "not issued",
+			token: func(t *testing.T, vt *verificationTest) string {
+				token, err := pkg.GenerateEmailVerificationToken(vt.user.ID, 24, vt.jwt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+			wantStatus: fiber.StatusBadRequest,
+			wantCode:   "invalid_verification_token",
+		},
+		{
+			name: "access token",
+			token: func(t *testing.T, vt *verificationTest) string {
+				token, err := pkg.GenerateAccessToken(vt.user.ID, vt.user.Role, 1, "", vt.jwt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+			wantStatus: fiber.StatusBadRequest,
+			wantCode:   "invalid_verification_token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vt := newVerificationTest(t, true)
+
+			status, code := vt.verify(t, tt.token(t, vt))
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Fatalf("status = %d, code = %q, want %d and %q", status, code, tt.wantStatus, tt.wantCode)
+			}
+			if got := vt.emailVerified(t, vt.user); got != tt.wantVerified {
+				t.Errorf("user email verified = %v, want %v", got, tt.wantVerified)
+			}
+			if got := vt.emailVerified(t, vt.other); got != tt.wantOtherVerified {
+				t.Errorf("other user email verified = %v, want %v", got, tt.wantOtherVerified)
+			}
+		})
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	t.Run("required", func(t *testing.T) {
+		vt := newVerificationTest(t, true)
+
+		if status, code := vt.favorites(t, vt.user); status != fiber.StatusForbidden || code != "email_not_verified" {
+			t.Fatalf("before verification: status = %d, code = %q, want %d and email_not_verified", status, code, fiber.StatusForbidden)
+		}
+
+		token := vt.verificationToken(t, vt.user.ID, vt.user.ID, time.Now().Add(time.Hour))
+		if status, code := vt.verify(t, token); status != fiber.StatusOK {
+			t.Fatalf("verify: status = %d, code = %q", status, code)
+		}
+		if status, code := vt.favorites(t, vt.user); status != fiber.StatusOK {
+			t.Fatalf("after verification: status = %d, code = %q, want %d", status, code, fiber.StatusOK)
+		}
+	})
+
+	t.Run("not required", func(t *testing.T) {
+		vt := newVerificationTest(t, false)
+
+		if status, code := vt.favorites(t, vt.user); status != fiber.StatusOK {
+			t.Fatalf("status = %d, code = %q, want %d", status, code, fiber.StatusOK)
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package images
+
+import "bytes"
+
+// Поддерживаемые форматы изображений
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+	FormatWebP = "webp"
+)
+
+// DetectFormat Определение формата по сигнатуре (magic bytes) в начале файла.
+// Возвращает пустую строку, если формат не распознан
+func DetectFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return FormatJPEG
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return FormatGIF
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return FormatWebP
+	}
+
+	return ""
+}
+
+func formatAllowed(format string, allowed []string) bool {
+	if format == "" {
+		return false
+	}
+	for _, f := range allowed {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package images
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Rendition размер изображения, который генерируется для каждой загрузки
+type Rendition struct {
+	Name    string
+	MaxSide int // максимальная длина большей стороны в пикселях
+}
+
+// Renditions Набор генерируемых размеров
+var Renditions = []Rendition{
+	{Name: "thumbnail", MaxSide: 160},
+	{Name: "card", MaxSide: 480},
+	{Name: "full", MaxSide: 1600},
+}
+
+// jpegQuality качество перекодирования
+const jpegQuality = 85
+
+var (
+	// ErrDecode загруженный файл не удалось декодировать
+	ErrDecode = errors.New("failed to decode image")
+	// ErrUnsupportedFormat формат файла не входит в список разрешенных
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	// ErrTooLarge размер файла превышает допустимый
+	ErrTooLarge = errors.New("image file is too large")
+	// ErrDimensionsTooLarge ширина, высота или число пикселей изображения превышает допустимое
+	ErrDimensionsTooLarge = errors.New("image dimensions are too large")
+)
+
+// Limits Ограничения на загружаемые изображения
+type Limits struct {
+	MaxBytes       int64
+	MaxWidth       int
+	MaxHeight      int
+	MaxPixels      int      // ширина * высота, ограничивает память под декодированное изображение
+	AllowedFormats []string // jpeg, png, gif, webp
+}
+
+// Processed Результат обработки одного размера
+type Processed struct {
+	Rendition string
+	Key       string // имя файла по хэшу содержимого: <sha256>.jpg
+	Data      []byte
+}
+
+// Process Проверка и декодирование загруженного изображения, генерация всех размеров.
+// Формат определяется по сигнатуре файла, а не по заявленному клиентом Content-Type.
+// Изображение перекодируется заново, поэтому EXIF и другие метаданные не сохраняются;
+// ориентация из EXIF применяется к пикселям до удаления метаданных.
+// У анимированных GIF используется первый кадр
+func Process(r io.Reader, limits Limits) ([]Processed, error) {
+	raw, err := io.ReadAll(io.LimitReader(r, limits.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(raw)) > limits.MaxBytes {
+		return nil, ErrTooLarge
+	}
+
+	format := DetectFormat(raw)
+	if !formatAllowed(format, limits.AllowedFormats) {
+		return nil, ErrUnsupportedFormat
+	}
+
+	// Размеры проверяются по заголовку до декодирования, чтобы не выделять память под огромные изображения.
+	// Декодированное изображение занимает до 4 байт на пиксель, а поворот по EXIF создает еще одну копию
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	if cfg.Width > limits.MaxWidth || cfg.Height > limits.MaxHeight ||
+		int64(cfg.Width)*int64(cfg.Height) > int64(limits.MaxPixels) {
+		return nil, ErrDimensionsTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	src = applyOrientation(src, readOrientation(raw))
+
+	result := make([]Processed, 0, len(Renditions))
+	for _, rendition := range Renditions {
+		data, err := encodeJPEG(resize(src, rendition.MaxSide))
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(data)
+		result = append(result, Processed{
+			Rendition: rendition.Name,
+			Key:       hex.EncodeToString(sum[:]) + ".jpg",
+			Data:      data,
+		})
+	}
+
+	return result, nil
+}
+
+// resize Уменьшение изображения так, чтобы большая сторона не превышала maxSide.
+// Изображения меньше заданного размера не увеличиваются
+func resize(src image.Image, maxSide int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			h = max(1, h*maxSide/w)
+			w = maxSide
+		} else {
+			w = max(1, w*maxSide/h)
+			h = maxSide
+		}
+	}
+
+	// Прозрачные области заливаются белым, так как JPEG не поддерживает альфа-канал
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	return dst
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
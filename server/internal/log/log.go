@@ -1,8 +1,8 @@
 package log
 
 import (
-	"fmt"
-	"github.com/gofiber/fiber/v2"
+	"context"
+	"io"
 	"os"
 	"server/internal/config"
 	"time"
@@ -11,73 +11,81 @@ import (
 	"github.com/rs/zerolog"
 )
 
-type LogsField struct {
-	Level  string
-	Method string
-	Url    string
-	Status int
-}
+// InitLogger Создание логера по настройкам cfg.Log. Возвращаемый io.Closer закрывает файл лога
+// при остановке приложения
+func InitLogger(cfg *config.Config) (*zerolog.Logger, io.Closer) {
+	logCfg := cfg.Log
 
-func InitLogger() *zerolog.Logger {
-	if config.ProductionType == "prod" {
-		lumberjackLogger := &lumberjack.Logger{
-			Filename:   "/var/log/app/iqj.log",
-			MaxSize:    1024,
-			MaxAge:     183,
-			MaxBackups: 5,
-			Compress:   true,
+	level := logCfg.Level
+	if level == "" {
+		level = "debug"
+		if cfg.IsProduction() {
+			level = "info"
 		}
-
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
-		logger := zerolog.New(lumberjackLogger).With().Timestamp().Logger()
-
-		return &logger
 	}
-	logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
+	// Уровень проверен при загрузке конфигурации
+	_ = SetLevel(level)
 
-	return &logger
-}
+	output := logCfg.Output
+	if output == "" {
+		output = "stdout"
+		if cfg.IsProduction() {
+			output = "file"
+		}
+	}
 
-func CreateLog(log *zerolog.Logger, field LogsField) *zerolog.Event {
-	var event *zerolog.Event
-	if field.Level == "Info" {
-		event = log.Info().Caller()
-	} else if field.Level == "Error" {
-		event = log.Error().Caller()
-	} else if field.Level == "Warn" {
-		event = log.Warn().Caller()
-	} else if field.Level == "Debug" {
-		event = log.Debug().Caller()
-	} else if field.Level == "Fatal" {
-		event = log.Fatal().Caller()
-	} else {
-		fmt.Println("Unknown log level")
-		return nil
+	var writers []io.Writer
+	var closer io.Closer = io.NopCloser(nil)
+	if output == "stdout" || output == "both" {
+		var stdout io.Writer = os.Stdout
+		if logCfg.Format == "console" {
+			stdout = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+		}
+		writers = append(writers, stdout)
+	}
+	if output == "file" || output == "both" {
+		file := &lumberjack.Logger{
+			Filename:   logCfg.File.Path,
+			MaxSize:    logCfg.File.MaxSize,
+			MaxAge:     logCfg.File.MaxAge,
+			MaxBackups: logCfg.File.MaxBackups,
+			Compress:   logCfg.File.Compress,
+		}
+		writers = append(writers, file)
+		closer = file
 	}
 
-	event.Str("method", field.Method).Str("url", field.Url).Int("status", field.Status)
+	logger := zerolog.New(zerolog.MultiLevelWriter(writers...)).With().Timestamp().Logger()
+	if sampling := logCfg.Sampling; sampling.Burst > 0 {
+		// Предупреждения и ошибки не прореживаются
+		sampler := &zerolog.BurstSampler{
+			Burst:       uint32(sampling.Burst),
+			Period:      time.Duration(sampling.Period) * time.Second,
+			NextSampler: &zerolog.BasicSampler{N: uint32(sampling.Every)},
+		}
+		logger = logger.Sample(zerolog.LevelSampler{TraceSampler: sampler, DebugSampler: sampler, InfoSampler: sampler})
+	}
+	zerolog.DefaultContextLogger = &logger
 
-	return event
+	return &logger, closer
 }
 
-func RequestLogger(log *zerolog.Logger) fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		log.Info().
-			Str("method", c.Method()).
-			Str("url", c.OriginalURL()).
-			Msg("incoming request")
-
-		start := time.Now()
-		defer func() {
-			if time.Since(start) > time.Second*2 {
-				log.Warn().
-					Str("method", c.Method()).
-					Str("url", c.OriginalURL()).
-					Dur("elapsed_ms", time.Since(start)).
-					Msg("long response time")
-			}
-		}()
+// Level Текущий уровень логирования
+func Level() string {
+	return zerolog.GlobalLevel().String()
+}
 
-		return c.Next()
+// SetLevel Изменение уровня логирования для всех логеров приложения без перезапуска
+func SetLevel(level string) error {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return err
 	}
+	zerolog.SetGlobalLevel(lvl)
+	return nil
+}
+
+// Ctx Логер запроса из контекста. Вне запроса возвращается общий логер приложения
+func Ctx(ctx context.Context) *zerolog.Logger {
+	return zerolog.Ctx(ctx)
 }
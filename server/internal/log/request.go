@@ -0,0 +1,127 @@
+package log
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+const (
+	// RequestIDKey Ключ c.Locals с ID запроса
+	RequestIDKey = "requestid"
+	// errorKey Ключ c.Locals с ошибкой, которой завершился запрос
+	errorKey = "log_error"
+	// maxRequestIDLength Максимальная длина ID запроса из заголовка
+	maxRequestIDLength = 128
+	// slowRequestThreshold Запросы дольше этого времени логируются с уровнем Warn
+	slowRequestThreshold = 2 * time.Second
+)
+
+// requestError Ошибка запроса для строки лога доступа
+type requestError struct {
+	code string
+	err  error
+}
+
+// RequestID Middleware ID запроса. ID берется из заголовка X-Request-ID, если он задан и корректен,
+// иначе генерируется. ID возвращается в заголовке ответа и сохраняется в c.Locals(RequestIDKey)
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(fiber.HeaderXRequestID)
+		if !validRequestID(id) {
+			id = utils.UUIDv4()
+		}
+
+		c.Set(fiber.HeaderXRequestID, id)
+		c.Locals(RequestIDKey, id)
+		return c.Next()
+	}
+}
+
+// validRequestID ID из заголовка попадает в логи, поэтому допускаются только
+// печатные ASCII символы без пробелов и кавычек
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' || id[i] == '"' || id[i] == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestLogger Middleware логера запроса. Логер с ID запроса и трассы передается через
+// c.UserContext() и доступен в ручках через Ctx. По завершении запроса пишется одна строка лога
+// доступа с маршрутом, кодом ответа, временем обработки, размером ответа и ошибкой, если она была
+func RequestLogger(log *zerolog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		requestID, _ := c.Locals(RequestIDKey).(string)
+		logCtx := log.With().Str("request_id", requestID)
+		if sc := trace.SpanContextFromContext(c.UserContext()); sc.IsValid() {
+			logCtx = logCtx.Str("trace_id", sc.TraceID().String()).Str("span_id", sc.SpanID().String())
+		}
+		logger := logCtx.Logger()
+		c.SetUserContext(logger.WithContext(c.UserContext()))
+
+		// Ошибка ручки к этому моменту уже преобразована в ответ, поэтому в лог попадает код
+		// отправленного ответа, а сама ошибка передается дальше без изменений
+		err := c.Next()
+
+		latency := time.Since(start)
+		status := c.Response().StatusCode()
+
+		// Логер берется из контекста повторно: после проверки токена в нем есть user_id
+		event := accessLogEvent(Ctx(c.UserContext()), status, latency)
+		event.Str("method", c.Method()).
+			Str("route", c.Route().Path).
+			Str("url", c.OriginalURL()).
+			Int("status", status).
+			Dur("latency_ms", latency).
+			Int("bytes_out", responseSize(c)).
+			Str("ip", c.IP())
+		if reqErr, ok := c.Locals(errorKey).(requestError); ok {
+			event.Str("code", reqErr.code).AnErr("error", reqErr.err)
+		} else if err != nil {
+			event.AnErr("error", err)
+		}
+		event.Msg("request")
+
+		return err
+	}
+}
+
+// accessLogEvent Уровень строки лога доступа: Error для 5xx, Warn для 4xx и медленных запросов
+func accessLogEvent(logger *zerolog.Logger, status int, latency time.Duration) *zerolog.Event {
+	switch {
+	case status >= fiber.StatusInternalServerError:
+		return logger.Error()
+	case status >= fiber.StatusBadRequest, latency > slowRequestThreshold:
+		return logger.Warn()
+	}
+	return logger.Info()
+}
+
+// responseSize Размер тела ответа. Потоковое тело не читается, размер берется из заголовка
+func responseSize(c *fiber.Ctx) int {
+	if c.Response().IsBodyStream() {
+		return c.Response().Header.ContentLength()
+	}
+	return len(c.Response().Body())
+}
+
+// WithUserID Добавление ID пользователя в логер запроса после проверки токена
+func WithUserID(c *fiber.Ctx, userID int) {
+	logger := Ctx(c.UserContext()).With().Int("user_id", userID).Logger()
+	c.SetUserContext(logger.WithContext(c.UserContext()))
+}
+
+// SetError Сохранение ошибки, которой завершился запрос, для строки лога доступа
+func SetError(c *fiber.Ctx, code string, err error) {
+	c.Locals(errorKey, requestError{code: code, err: err})
+}
@@ -0,0 +1,15 @@
+package mail
+
+import (
+	"context"
+	"server/internal/log"
+)
+
+// Log Драйвер для разработки: письмо не отправляется, а пишется в лог целиком вместе со ссылками
+type Log struct{}
+
+func (Log) Send(ctx context.Context, msg Message) error {
+	log.Ctx(ctx).Info().Str("to", msg.To).Str("subject", msg.Subject).Str("body", msg.Body).
+		Msg("email written to log instead of sending")
+	return nil
+}
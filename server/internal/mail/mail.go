@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"server/internal/config"
+)
+
+// Message Текстовое письмо одному получателю
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer Отправка писем пользователям
+type Mailer interface {
+	// Send отправка письма; возвращает ошибку, если сервер не принял письмо
+	Send(ctx context.Context, msg Message) error
+}
+
+// New Создание отправителя по настройкам: log или smtp
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "log":
+		return Log{}, nil
+	case "smtp":
+		return NewSMTP(cfg.SMTP, cfg.From)
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+}
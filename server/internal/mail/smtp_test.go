@@ -0,0 +1,202 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"server/internal/config"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP Локальный SMTP сервер, принимающий одно письмо без авторизации и TLS
+type fakeSMTP struct {
+	listener net.Listener
+	// rcptReply ответ на RCPT TO, по умолчанию письмо принимается
+	rcptReply string
+
+	from string
+	to   []string
+	data string
+	done chan struct{}
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	return &fakeSMTP{listener: listener, rcptReply: "250 OK", done: make(chan struct{})}
+}
+
+// config Настройки драйвера для подключения к серверу
+func (s *fakeSMTP) config(t *testing.T) config.SMTPConfig {
+	t.Helper()
+
+	host, port, err := net.SplitHostPort(s.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := net.LookupPort("tcp", port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.SMTPConfig{Host: host, Port: p, TLS: "none", Timeout: 5}
+}
+
+// serve Обработка одного соединения до QUIT
+func (s *fakeSMTP) serve(t *testing.T) {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	reply := func(line string) {
+		if err := tp.PrintfLine("%s", line); err != nil {
+			t.Error(err)
+		}
+	}
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			reply("250 fake")
+		case "MAIL":
+			s.from = arg
+			reply("250 OK")
+		case "RCPT":
+			s.to = append(s.to, arg)
+			reply(s.rcptReply)
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			s.data = string(data)
+			reply("250 OK")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPSend(t *testing.T) {
+	server := newFakeSMTP(t)
+	go server.serve(t)
+
+	sender, err := NewSMTP(server.config(t), "Kotiki <no-reply@kotiki.local>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link := "http://localhost/verify-email.html?token=" + strings.Repeat("a1b2", 40)
+	msg := Message{
+		To:      "Петр Петров <petrov@mail.ru>",
+		Subject: "Подтверждение email на Kotiki",
+		Body:    "Здравствуйте, Петр!\n\nПерейдите по ссылке:\n" + link + "\n.\nСсылка действует 24 ч.\n",
+	}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	<-server.done
+
+	if server.from != "FROM:<no-reply@kotiki.local>" {
+		t.Errorf("MAIL %s, want FROM:<no-reply@kotiki.local>", server.from)
+	}
+	if len(server.to) != 1 || server.to[0] != "TO:<petrov@mail.ru>" {
+		t.Errorf("RCPT %v, want [TO:<petrov@mail.ru>]", server.to)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(server.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Заголовки содержат только ASCII: тема в кодировке RFC 2047
+	rawSubject := parsed.Header.Get("Subject")
+	if !strings.HasPrefix(rawSubject, "=?utf-8?q?") {
+		t.Errorf("Subject %q is not RFC 2047 Q-encoded", rawSubject)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(rawSubject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != msg.Subject {
+		t.Errorf("Subject = %q, want %q", subject, msg.Subject)
+	}
+	to, err := parsed.Header.AddressList("To")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(to) != 1 || to[0].Name != "Петр Петров" || to[0].Address != "petrov@mail.ru" {
+		t.Errorf("To = %v, want Петр Петров <petrov@mail.ru>", to)
+	}
+	if id := parsed.Header.Get("Message-ID"); !strings.HasSuffix(id, "@kotiki.local>") {
+		t.Errorf("Message-ID %q is not in the sender domain", id)
+	}
+
+	if cte := parsed.Header.Get("Content-Transfer-Encoding"); cte != "quoted-printable" {
+		t.Fatalf("Content-Transfer-Encoding = %q, want quoted-printable", cte)
+	}
+	raw, err := io.ReadAll(parsed.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ReadDotBytes возвращает строки с LF вместо CRLF
+	for _, line := range strings.Split(string(raw), "\n") {
+		if len(line) > 76 {
+			t.Errorf("encoded line longer than 76 characters: %q", line)
+		}
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(string(raw))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(body); got != msg.Body {
+		t.Errorf("body = %q, want %q", got, msg.Body)
+	}
+}
+
+func TestSMTPSendRejected(t *testing.T) {
+	server := newFakeSMTP(t)
+	server.rcptReply = "550 No such user"
+	go server.serve(t)
+
+	sender, err := NewSMTP(server.config(t), "no-reply@kotiki.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sender.Send(context.Background(), Message{To: "nobody@mail.ru", Subject: "Тест", Body: "Тест"})
+	<-server.done
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Fatalf("Send() error = %v, want rejected recipient", err)
+	}
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"strconv"
+	"time"
+)
+
+// namespace Префикс имен метрик приложения
+const namespace = "kotiki"
+
+// Исходы операций в метке result
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Registry Реестр метрик приложения, отдается ручкой /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests число обработанных запросов по маршруту и коду ответа
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of handled HTTP requests by route and status.",
+	}, []string{"method", "route", "status"})
+	// HTTPRequestDuration время обработки запросов по маршруту и коду ответа
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and status.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"method", "route", "status"})
+	// ImageUploadBytes объем загруженных изображений до перекодирования
+	ImageUploadBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "image_upload_bytes_total",
+		Help:      "Total size of uploaded image files in bytes.",
+	})
+	// ImageUploads число загрузок изображений по исходу
+	ImageUploads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "image_uploads_total",
+		Help:      "Number of image uploads by result.",
+	}, []string{"result"})
+	// Logins число попыток входа по исходу
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Number of login attempts by result.",
+	}, []string{"result"})
+	// RateLimited число отклоненных или задержанных лимитером попыток входа и регистрации по причине
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Number of login and signup attempts rejected or delayed by the rate limiter by reason.",
+	}, []string{"reason"})
+	// AccountLockouts число временных блокировок входа после повторных неудач
+	AccountLockouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "account_lockouts_total",
+		Help:      "Number of temporary account lockouts after repeated failed logins.",
+	})
+	// FavoriteOperations число операций со списком любимых кошек по операции и исходу
+	FavoriteOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "favorite_operations_total",
+		Help:      "Number of favorite list operations by operation and result.",
+	}, []string{"operation", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		ImageUploadBytes,
+		ImageUploads,
+		Logins,
+		RateLimited,
+		AccountLockouts,
+		FavoriteOperations,
+	)
+}
+
+// RegisterDB Регистрация статистики пула соединений с бд
+func RegisterDB(db *sqlx.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db.DB, name))
+}
+
+// Result Значение метки result по ошибке операции
+func Result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
+
+// Handler Ручка отдачи метрик в формате Prometheus
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
+
+// Middleware Учет числа и времени обработки запросов. Метка route - шаблон маршрута,
+// а не URL, чтобы число рядов не зависело от ID в путях
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		// Ответ на ошибку уже отправлен внутренним middleware, поэтому код ответа итоговый,
+		// а ошибка передается дальше без изменений
+		err := c.Next()
+
+		labels := []string{c.Method(), c.Route().Path, strconv.Itoa(c.Response().StatusCode())}
+		HTTPRequests.WithLabelValues(labels...).Inc()
+		HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
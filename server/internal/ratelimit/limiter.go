@@ -0,0 +1,174 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"server/internal/config"
+	"server/util"
+	"strings"
+	"time"
+)
+
+// Причины отказа в ExceededError. ReasonDelay не отказ, а задержка попытки, она используется
+// только как метка метрики
+const (
+	ReasonIP     = "ip"
+	ReasonEmail  = "email"
+	ReasonDelay  = "delay"
+	ReasonLocked = "locked"
+)
+
+// ExceededError Попытка отклонена лимитером. Повторить ее можно через RetryAfter
+type ExceededError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("rate limit exceeded (%s), retry after %s", e.Reason, e.RetryAfter)
+}
+
+// Limiter Защита входа и регистрации от перебора: лимиты попыток по IP и email в скользящем
+// окне, прогрессивная задержка попыток после неудачных входов и временная блокировка email
+type Limiter struct {
+	store Store
+	cfg   config.RateLimitConfig
+	now   func() time.Time
+}
+
+// NewLimiter Создание лимитера. При выключенном cfg.Enabled все попытки разрешаются
+func NewLimiter(store Store, cfg config.RateLimitConfig) *Limiter {
+	return &Limiter{store: store, cfg: cfg, now: time.Now}
+}
+
+// Allow Учет попытки с адреса ip на email. Возвращает задержку, которую нужно выждать перед
+// проверкой пароля, *ExceededError, если попытку нужно отклонить, или ошибку хранилища.
+// Отклоненные попытки не учитываются, поэтому повтор через RetryAfter будет принят. Задержанные
+// попытки учитываются, поэтому параллельные попытки во время задержки ограничены лимитами окон
+func (l *Limiter) Allow(ctx context.Context, ip, email string) (time.Duration, error) {
+	if !l.cfg.Enabled {
+		return 0, nil
+	}
+	now := l.now()
+	email = emailKey(email)
+
+	until, err := l.store.LockedUntil(ctx, "lock:"+email, now)
+	if err != nil {
+		return 0, err
+	}
+	if !until.IsZero() {
+		return 0, &ExceededError{Reason: ReasonLocked, RetryAfter: until.Sub(now)}
+	}
+
+	if err := l.check(ctx, ReasonIP, "ip:"+ip, now, l.cfg.IP); err != nil {
+		return 0, err
+	}
+	if err := l.check(ctx, ReasonEmail, "email:"+email, now, l.cfg.Email); err != nil {
+		return 0, err
+	}
+
+	failures, err := l.store.Peek(ctx, "fail:"+email, now, seconds(l.cfg.FailureWindow))
+	if err != nil {
+		return 0, err
+	}
+
+	// Между проверкой и записью другие экземпляры могут успеть записать свои попытки,
+	// поэтому лимит может быть превышен на число одновременных запросов
+	if _, err := l.store.Hit(ctx, "ip:"+ip, now, seconds(l.cfg.IP.Window)); err != nil {
+		return 0, err
+	}
+	if _, err := l.store.Hit(ctx, "email:"+email, now, seconds(l.cfg.Email.Window)); err != nil {
+		return 0, err
+	}
+
+	// Задержка отсчитывается от последней неудачи: попытка сразу после неудачи ждет дольше всего
+	if wait := failures.Newest.Add(l.delay(failures.Count)).Sub(now); failures.Count > 0 && wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// Wait Ожидание задержки попытки. Прерывается с ошибкой контекста, если запрос отменен
+// или истекло время его обработки
+func Wait(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// check Проверка, что в окне key есть место для еще одной попытки
+func (l *Limiter) check(ctx context.Context, reason, key string, now time.Time, limit config.RateLimitWindow) error {
+	window := seconds(limit.Window)
+	w, err := l.store.Peek(ctx, key, now, window)
+	if err != nil {
+		return err
+	}
+	if w.Count >= limit.Limit {
+		return &ExceededError{Reason: reason, RetryAfter: w.Oldest.Add(window).Sub(now)}
+	}
+	return nil
+}
+
+// Failure Учет неудачного входа на email. После lockout_after неудач email блокируется,
+// тогда возвращается true
+func (l *Limiter) Failure(ctx context.Context, email string) (bool, error) {
+	if !l.cfg.Enabled {
+		return false, nil
+	}
+	now := l.now()
+	email = emailKey(email)
+
+	failures, err := l.store.Hit(ctx, "fail:"+email, now, seconds(l.cfg.FailureWindow))
+	if err != nil {
+		return false, err
+	}
+	if l.cfg.LockoutAfter > 0 && failures.Count >= l.cfg.LockoutAfter {
+		if err := l.store.Lock(ctx, "lock:"+email, now.Add(seconds(l.cfg.LockoutDuration))); err != nil {
+			return false, err
+		}
+		// После блокировки задержки отсчитываются заново
+		return true, l.store.Reset(ctx, "fail:"+email)
+	}
+	return false, nil
+}
+
+// Success Сброс неудачных входов на email после успешного входа
+func (l *Limiter) Success(ctx context.Context, email string) error {
+	if !l.cfg.Enabled {
+		return nil
+	}
+	return l.store.Reset(ctx, "fail:"+emailKey(email))
+}
+
+// delay Задержка перед следующей попыткой после failures неудач: delay_base, удваивающаяся
+// с каждой неудачей сверх delay_after, но не больше delay_max
+func (l *Limiter) delay(failures int) time.Duration {
+	if l.cfg.DelayBase <= 0 || failures <= l.cfg.DelayAfter {
+		return 0
+	}
+
+	delay, limit := seconds(l.cfg.DelayBase), seconds(l.cfg.DelayMax)
+	for i := l.cfg.DelayAfter + 1; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// emailKey Ключ email без учета регистра. Хранится хэш, чтобы адреса не попадали в хранилище
+func emailKey(email string) string {
+	return util.HashToken(strings.ToLower(strings.TrimSpace(email)))
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
@@ -0,0 +1,227 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"server/internal/config"
+	"testing"
+	"time"
+)
+
+// clock Управляемые часы для лимитера
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func testConfig() config.RateLimitConfig {
+	return config.RateLimitConfig{
+		Enabled:         true,
+		IP:              config.RateLimitWindow{Limit: 3, Window: 60},
+		Email:           config.RateLimitWindow{Limit: 100, Window: 60},
+		FailureWindow:   900,
+		DelayAfter:      3,
+		DelayBase:       1,
+		DelayMax:        30,
+		LockoutAfter:    10,
+		LockoutDuration: 900,
+	}
+}
+
+// newTestLimiter Лимитер поверх NewMemory с часами clock
+func newTestLimiter(cfg config.RateLimitConfig) (*Limiter, *Memory, *clock) {
+	c := &clock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMemory()
+	l := NewLimiter(store, cfg)
+	l.now = c.Now
+	return l, store, c
+}
+
+// exceeded Проверка, что err - *ExceededError с причиной reason и ожиданием retryAfter
+func exceeded(t *testing.T, err error, reason string, retryAfter time.Duration) {
+	t.Helper()
+
+	var e *ExceededError
+	if !errors.As(err, &e) {
+		t.Fatalf("error = %v, want *ExceededError", err)
+	}
+	if e.Reason != reason || e.RetryAfter != retryAfter {
+		t.Fatalf("got reason %q retry after %s, want %q and %s", e.Reason, e.RetryAfter, reason, retryAfter)
+	}
+}
+
+// allowErr Ошибка Allow без задержки
+func allowErr(ctx context.Context, l *Limiter, ip, email string) error {
+	_, err := l.Allow(ctx, ip, email)
+	return err
+}
+
+func TestLimiterDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      int
+		failures  int
+		wantDelay time.Duration
+	}{
+		{name: "no failures", base: 1, failures: 0, wantDelay: 0},
+		{name: "up to delay_after", base: 1, failures: 3, wantDelay: 0},
+		{name: "first delayed", base: 1, failures: 4, wantDelay: time.Second},
+		{name: "doubles", base: 1, failures: 5, wantDelay: 2 * time.Second},
+		{name: "doubles again", base: 1, failures: 8, wantDelay: 16 * time.Second},
+		{name: "capped by delay_max", base: 1, failures: 9, wantDelay: 30 * time.Second},
+		{name: "stays capped", base: 1, failures: 1000, wantDelay: 30 * time.Second},
+		{name: "base above max", base: 60, failures: 4, wantDelay: 30 * time.Second},
+		{name: "disabled", base: 0, failures: 8, wantDelay: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.DelayBase = tt.base
+			l, _, _ := newTestLimiter(cfg)
+
+			if got := l.delay(tt.failures); got != tt.wantDelay {
+				t.Fatalf("delay(%d) = %s, want %s", tt.failures, got, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestLimiterIPWindow(t *testing.T) {
+	ctx := context.Background()
+	l, _, c := newTestLimiter(testConfig())
+
+	for i, email := range []string{"a@mail.ru", "b@mail.ru", "c@mail.ru"} {
+		if _, err := l.Allow(ctx, "10.0.0.1", email); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+		c.Advance(10 * time.Second)
+	}
+
+	// Место освободится, когда первая попытка выйдет из окна
+	exceeded(t, allowErr(ctx, l, "10.0.0.1", "other@mail.ru"), ReasonIP, 30*time.Second)
+	if _, err := l.Allow(ctx, "10.0.0.2", "other@mail.ru"); err != nil {
+		t.Fatalf("other ip: %v", err)
+	}
+
+	// Отклоненные попытки не учитываются, поэтому повтор через RetryAfter принимается
+	c.Advance(30 * time.Second)
+	if _, err := l.Allow(ctx, "10.0.0.1", "other@mail.ru"); err != nil {
+		t.Fatalf("after retry after: %v", err)
+	}
+}
+
+func TestLimiterFailures(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig()
+	cfg.IP.Limit = 1000
+	l, store, c := newTestLimiter(cfg)
+	const email = "petrov@mail.ru"
+
+	fail := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			locked, err := l.Failure(ctx, email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if locked {
+				t.Fatalf("locked after failure %d", i+1)
+			}
+		}
+	}
+
+	fail(3)
+	if _, err := l.Allow(ctx, "10.0.0.1", email); err != nil {
+		t.Fatalf("before delay_after: %v", err)
+	}
+
+	fail(2)
+	c.Advance(500 * time.Millisecond)
+	// Регистр и пробелы в email не влияют на ключ. Задержка отсчитывается от последней неудачи
+	if delay, err := l.Allow(ctx, "10.0.0.1", " Petrov@Mail.ru"); err != nil || delay != 1500*time.Millisecond {
+		t.Fatalf("Allow() = %s, %v, want %s delay", delay, err, 1500*time.Millisecond)
+	}
+	c.Advance(1500 * time.Millisecond)
+	if delay, err := l.Allow(ctx, "10.0.0.1", email); err != nil || delay != 0 {
+		t.Fatalf("after delay: %s, %v", delay, err)
+	}
+
+	fail(4)
+	locked, err := l.Failure(ctx, email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !locked {
+		t.Fatal("not locked after lockout_after failures")
+	}
+	if w, _ := store.Peek(ctx, "fail:"+emailKey(email), c.Now(), time.Hour); w.Count != 0 {
+		t.Fatalf("failures not reset after lockout: %d", w.Count)
+	}
+
+	c.Advance(time.Minute)
+	exceeded(t, allowErr(ctx, l, "10.0.0.1", email), ReasonLocked, 14*time.Minute)
+
+	// После блокировки задержки отсчитываются заново
+	c.Advance(14 * time.Minute)
+	if _, err := l.Allow(ctx, "10.0.0.1", email); err != nil {
+		t.Fatalf("after lockout: %v", err)
+	}
+}
+
+func TestLimiterSuccessResetsFailures(t *testing.T) {
+	ctx := context.Background()
+	l, _, _ := newTestLimiter(testConfig())
+	const email = "petrov@mail.ru"
+
+	for i := 0; i < 5; i++ {
+		if _, err := l.Failure(ctx, email); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Success(ctx, email); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Allow(ctx, "10.0.0.1", email); err != nil {
+		t.Fatalf("after success: %v", err)
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig()
+	cfg.Enabled = false
+	l, _, _ := newTestLimiter(cfg)
+
+	for i := 0; i < 20; i++ {
+		if locked, err := l.Failure(ctx, "petrov@mail.ru"); err != nil || locked {
+			t.Fatalf("Failure() = %v, %v", locked, err)
+		}
+		if _, err := l.Allow(ctx, "10.0.0.1", "petrov@mail.ru"); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestWait(t *testing.T) {
+	if err := Wait(context.Background(), 10*time.Millisecond); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := Wait(ctx, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Wait() returned after %s, want on context deadline", elapsed)
+	}
+}
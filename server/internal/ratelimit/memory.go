@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval Период удаления устаревших попыток и блокировок из памяти
+const sweepInterval = time.Minute
+
+// Memory Хранилище в памяти процесса. Счетчики не разделяются между экземплярами
+// и сбрасываются при перезапуске
+type Memory struct {
+	mu    sync.Mutex
+	hits  map[string][]time.Time
+	locks map[string]time.Time
+	// maxWindow самое длинное окно из запрошенных, попытки старше него не нужны ни одному ключу
+	maxWindow time.Duration
+	lastSweep time.Time
+}
+
+// NewMemory Создание хранилища в памяти
+func NewMemory() *Memory {
+	return &Memory{
+		hits:  make(map[string][]time.Time),
+		locks: make(map[string]time.Time),
+	}
+}
+
+func (m *Memory) Hit(_ context.Context, key string, now time.Time, window time.Duration) (Window, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now, window)
+	hits := append(m.prune(key, now, window), now)
+	m.hits[key] = hits
+
+	return windowOf(hits), nil
+}
+
+func (m *Memory) Peek(_ context.Context, key string, now time.Time, window time.Duration) (Window, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return windowOf(m.prune(key, now, window)), nil
+}
+
+func (m *Memory) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.hits, key)
+	return nil
+}
+
+func (m *Memory) Lock(_ context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.locks[key] = until
+	return nil
+}
+
+func (m *Memory) LockedUntil(_ context.Context, key string, now time.Time) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	until, ok := m.locks[key]
+	if !ok || !until.After(now) {
+		delete(m.locks, key)
+		return time.Time{}, nil
+	}
+	return until, nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+// prune Удаление попыток ключа, вышедших из окна. Вызывается под m.mu
+func (m *Memory) prune(key string, now time.Time, window time.Duration) []time.Time {
+	hits := m.hits[key]
+	start := now.Add(-window)
+	i := 0
+	for i < len(hits) && !hits[i].After(start) {
+		i++
+	}
+	hits = hits[i:]
+	if len(hits) == 0 {
+		delete(m.hits, key)
+	} else {
+		m.hits[key] = hits
+	}
+	return hits
+}
+
+// sweep Периодическое удаление ключей без попыток в окне и истекших блокировок, чтобы
+// память не росла от разовых IP и email. Вызывается под m.mu
+func (m *Memory) sweep(now time.Time, window time.Duration) {
+	if window > m.maxWindow {
+		m.maxWindow = window
+	}
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key := range m.hits {
+		m.prune(key, now, m.maxWindow)
+	}
+	for key, until := range m.locks {
+		if !until.After(now) {
+			delete(m.locks, key)
+		}
+	}
+}
+
+// windowOf Состояние окна по упорядоченным попыткам
+func windowOf(hits []time.Time) Window {
+	if len(hits) == 0 {
+		return Window{}
+	}
+	return Window{Count: len(hits), Oldest: hits[0], Newest: hits[len(hits)-1]}
+}
@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryWindow(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	window := time.Minute
+
+	for _, offset := range []time.Duration{0, 20 * time.Second, 40 * time.Second} {
+		if _, err := m.Hit(ctx, "key", start.Add(offset), window); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		at     time.Duration
+		count  int
+		oldest time.Duration
+	}{
+		{name: "all in window", at: 59 * time.Second, count: 3, oldest: 0},
+		// Попытка ровно на границе окна уже не учитывается
+		{name: "first on boundary", at: time.Minute, count: 2, oldest: 20 * time.Second},
+		{name: "one left", at: 90 * time.Second, count: 1, oldest: 40 * time.Second},
+		{name: "all expired", at: 100 * time.Second, count: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := m.Peek(ctx, "key", start.Add(tt.at), window)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.Count != tt.count {
+				t.Fatalf("Count = %d, want %d", w.Count, tt.count)
+			}
+			if tt.count > 0 && !w.Oldest.Equal(start.Add(tt.oldest)) {
+				t.Fatalf("Oldest = %s, want %s", w.Oldest, start.Add(tt.oldest))
+			}
+			if tt.count > 0 && !w.Newest.Equal(start.Add(40*time.Second)) {
+				t.Fatalf("Newest = %s, want %s", w.Newest, start.Add(40*time.Second))
+			}
+		})
+	}
+
+	if _, ok := m.hits["key"]; ok {
+		t.Fatal("key without hits in window is kept")
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, err := m.Hit(ctx, "short", start, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Lock(ctx, "lock", start.Add(30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Hit(ctx, "other", start.Add(30*time.Second), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Hit(ctx, "long", start.Add(50*time.Second), 2*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ключи чистятся по самому длинному окну: через sweepInterval попытки ключа short
+	// еще не старше двух минут и остаются в памяти
+	if _, err := m.Hit(ctx, "new", start.Add(sweepInterval+time.Second), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.hits) != 4 {
+		t.Fatalf("swept keys within the longest window: %v", m.hits)
+	}
+	if len(m.locks) != 0 {
+		t.Fatalf("expired lock left after sweep: %v", m.locks)
+	}
+
+	// До следующей очистки устаревшие ключи остаются, после нее удаляются
+	if _, err := m.Hit(ctx, "new", start.Add(2*sweepInterval), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.hits) != 4 {
+		t.Fatalf("swept too early: %v", m.hits)
+	}
+	if _, err := m.Hit(ctx, "new", start.Add(2*sweepInterval+30*time.Second), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"long", "new"} {
+		if _, ok := m.hits[key]; !ok {
+			t.Fatalf("key %q swept", key)
+		}
+	}
+	if len(m.hits) != 2 {
+		t.Fatalf("stale keys left after sweep: %v", m.hits)
+	}
+}
+
+func TestMemoryLock(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	until := start.Add(time.Minute)
+
+	if err := m.Lock(ctx, "key", until); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Duration
+		want time.Time
+	}{
+		{name: "locked", at: 59 * time.Second, want: until},
+		{name: "expired", at: time.Minute, want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.LockedUntil(ctx, "key", start.Add(tt.at))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("LockedUntil() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"math/rand/v2"
+	"server/internal/config"
+	"strconv"
+	"time"
+)
+
+// keyPrefix Префикс ключей в Redis, чтобы счетчики не смешивались с другими данными
+const keyPrefix = "ratelimit:"
+
+// Redis Хранилище в Redis-совместимой бд, общее для всех экземпляров бэкенда. Попытки
+// хранятся в sorted set со временем попытки в микросекундах в качестве score
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis Подключение к Redis с проверкой доступности
+func NewRedis(cfg config.RateLimitRedis) (*Redis, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Address,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to redis %s: %w", cfg.Address, err)
+	}
+
+	return &Redis{client: client}, nil
+}
+
+func (r *Redis) Hit(ctx context.Context, key string, now time.Time, window time.Duration) (Window, error) {
+	return r.window(ctx, key, now, window, true)
+}
+
+func (r *Redis) Peek(ctx context.Context, key string, now time.Time, window time.Duration) (Window, error) {
+	return r.window(ctx, key, now, window, false)
+}
+
+// window Удаление вышедших из окна попыток, запись новой при hit и чтение окна одной транзакцией
+func (r *Redis) window(ctx context.Context, key string, now time.Time, window time.Duration, hit bool) (Window, error) {
+	key = keyPrefix + key
+
+	var count *redis.IntCmd
+	var oldest, newest *redis.ZSliceCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-window).UnixMicro(), 10))
+		if hit {
+			// Случайная часть не дает слиться попыткам с одинаковым временем
+			member := strconv.FormatInt(now.UnixMicro(), 10) + "-" + strconv.FormatUint(rand.Uint64(), 36)
+			pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixMicro()), Member: member})
+			pipe.PExpire(ctx, key, window)
+		}
+		count = pipe.ZCard(ctx, key)
+		oldest = pipe.ZRangeWithScores(ctx, key, 0, 0)
+		newest = pipe.ZRangeWithScores(ctx, key, -1, -1)
+		return nil
+	})
+	if err != nil {
+		return Window{}, fmt.Errorf("failed to update rate limit window %s: %w", key, err)
+	}
+
+	w := Window{Count: int(count.Val())}
+	if z := oldest.Val(); len(z) > 0 {
+		w.Oldest = time.UnixMicro(int64(z[0].Score))
+	}
+	if z := newest.Val(); len(z) > 0 {
+		w.Newest = time.UnixMicro(int64(z[0].Score))
+	}
+	return w, nil
+}
+
+func (r *Redis) Reset(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, keyPrefix+key).Err(); err != nil {
+		return fmt.Errorf("failed to reset rate limit key %s: %w", key, err)
+	}
+	return nil
+}
+
+func (r *Redis) Lock(ctx context.Context, key string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	if err := r.client.Set(ctx, keyPrefix+key, until.UnixMicro(), ttl).Err(); err != nil {
+		return fmt.Errorf("failed to lock rate limit key %s: %w", key, err)
+	}
+	return nil
+}
+
+func (r *Redis) LockedUntil(ctx context.Context, key string, now time.Time) (time.Time, error) {
+	until, err := r.client.Get(ctx, keyPrefix+key).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read rate limit lock %s: %w", key, err)
+	}
+
+	t := time.UnixMicro(until)
+	if !t.After(now) {
+		return time.Time{}, nil
+	}
+	return t, nil
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"server/internal/config"
+	"time"
+)
+
+// Window Попытки по ключу в скользящем окне
+type Window struct {
+	Count int
+	// Oldest самая ранняя попытка в окне: когда она выйдет из окна, освободится место
+	Oldest time.Time
+	// Newest последняя попытка
+	Newest time.Time
+}
+
+// Store Хранилище счетчиков попыток и блокировок. Экземпляры бэкенда с общим хранилищем
+// видят попытки друг друга
+type Store interface {
+	// Hit запись попытки по ключу и состояние окна с ее учетом
+	Hit(ctx context.Context, key string, now time.Time, window time.Duration) (Window, error)
+	// Peek состояние окна без записи попытки
+	Peek(ctx context.Context, key string, now time.Time, window time.Duration) (Window, error)
+	// Reset удаление попыток по ключу
+	Reset(ctx context.Context, key string) error
+	// Lock блокировка ключа до until
+	Lock(ctx context.Context, key string, until time.Time) error
+	// LockedUntil окончание блокировки ключа, нулевое время если ключ не заблокирован
+	LockedUntil(ctx context.Context, key string, now time.Time) (time.Time, error)
+	// Close освобождение соединений хранилища
+	Close() error
+}
+
+// NewStore Создание хранилища по настройкам: memory для одного экземпляра или redis
+// для нескольких
+func NewStore(cfg config.RateLimitConfig) (Store, error) {
+	switch cfg.Backend {
+	case "memory":
+		return NewMemory(), nil
+	case "redis":
+		return NewRedis(cfg.Redis)
+	}
+	return nil, fmt.Errorf("unknown rate limit backend %q", cfg.Backend)
+}
@@ -0,0 +1,616 @@
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"server/internal/apperror"
+	"server/internal/entities"
+	"server/internal/repository"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// store Общее состояние хранилищ: избранное ссылается на кошек, поэтому данные хранятся вместе
+type store struct {
+	mu        sync.RWMutex
+	cats      map[int]entities.Cat
+	users     map[int]entities.User
+	favorites map[int]entities.Favorite
+	lastID    int
+
+	// refreshTokens рефреш токены по хэшу
+	refreshTokens map[string]entities.RefreshToken
+	// revokedTokens отозванные аксес токены: jti -> время истечения
+	revokedTokens map[string]time.Time
+	// tokensRevokedAt время отзыва всех аксес токенов пользователя
+	tokensRevokedAt map[int]time.Time
+	// verifications токены подтверждения email по хэшу
+	verifications map[string]verificationToken
+}
+
+// verificationToken Токен подтверждения email
+type verificationToken struct {
+	userID    int
+	expiresAt time.Time
+	used      bool
+}
+
+// NewRepositories Хранилища в памяти процесса. Предназначены для тестов бизнес-логики без бд
+func NewRepositories() repository.Repositories {
+	s := &store{
+		cats:            make(map[int]entities.Cat),
+		users:           make(map[int]entities.User),
+		favorites:       make(map[int]entities.Favorite),
+		refreshTokens:   make(map[string]entities.RefreshToken),
+		revokedTokens:   make(map[string]time.Time),
+		tokensRevokedAt: make(map[int]time.Time),
+		verifications:   make(map[string]verificationToken),
+	}
+
+	return repository.Repositories{
+		Cats:               &CatRepository{s: s},
+		Users:              &UserRepository{s: s},
+		Favorites:          &FavoriteRepository{s: s},
+		Tokens:             &TokenRepository{s: s},
+		EmailVerifications: &EmailVerificationRepository{s: s},
+	}
+}
+
+// nextID Выдача идентификатора, вызывается под блокировкой на запись
+func (s *store) nextID() int {
+	s.lastID++
+	return s.lastID
+}
+
+// CatRepository Реализация repository.CatRepository в памяти
+type CatRepository struct {
+	s *store
+}
+
+func (r *CatRepository) Create(ctx context.Context, cat *entities.Cat) (*entities.Cat, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	cat.ID = r.s.nextID()
+	r.s.cats[cat.ID] = *cat
+	return cat, nil
+}
+
+func (r *CatRepository) ExistsID(ctx context.Context, id int) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	_, ok := r.s.cats[id]
+	return ok, nil
+}
+
+func (r *CatRepository) ExistsBreed(ctx context.Context, breed string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, cat := range r.s.cats {
+		if cat.Breed == breed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *CatRepository) Update(ctx context.Context, req *entities.UpdateCatRequest) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	cat, ok := r.s.cats[req.ID]
+	if !ok {
+		return apperror.NotFound("cat_not_found", "cat not found")
+	}
+	cat.Breed = req.Breed
+	cat.Fur = req.Fur
+	cat.Temper = req.Temper
+	cat.CareComplexity = req.CareComplexity
+	r.s.cats[req.ID] = cat
+	return nil
+}
+
+// Delete удаление кошки вместе с записями избранного, как ON DELETE CASCADE в бд
+func (r *CatRepository) Delete(ctx context.Context, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.cats[id]; !ok {
+		return apperror.NotFound("cat_not_found", "cat not found")
+	}
+	delete(r.s.cats, id)
+	for favID, fav := range r.s.favorites {
+		if fav.CatID == id {
+			delete(r.s.favorites, favID)
+		}
+	}
+	return nil
+}
+
+func (r *CatRepository) GetByID(ctx context.Context, id int) (*entities.Cat, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	cat, ok := r.s.cats[id]
+	if !ok {
+		return nil, apperror.NotFound("cat_not_found", "cat not found")
+	}
+	return &cat, nil
+}
+
+// List страница списка с той же фильтрацией и сортировкой, что и в бд.
+// Курсор содержит поле и направление сортировки и id последней записи страницы
+func (r *CatRepository) List(ctx context.Context, filter *entities.CatFilter) (*entities.CatList, error) {
+	less, ok := catLess[filter.Sort]
+	if !ok {
+		return nil, repository.ErrInvalidSort
+	}
+	desc := filter.Order == "desc"
+
+	r.s.mu.RLock()
+	var cats []entities.Cat
+	for _, cat := range r.s.cats {
+		if catMatches(cat, filter) {
+			cats = append(cats, cat)
+		}
+	}
+	r.s.mu.RUnlock()
+
+	sort.Slice(cats, func(i, j int) bool {
+		a, b := cats[i], cats[j]
+		if desc {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.ID < b.ID
+	})
+
+	list := &entities.CatList{Items: []entities.Cat{}, Total: len(cats)}
+
+	start := filter.Offset
+	if filter.Cursor != "" {
+		sortField, order, id, err := decodeCursor(filter.Cursor)
+		if err != nil || sortField != filter.Sort || order != filter.Order {
+			return nil, repository.ErrInvalidCursor
+		}
+		start = -1
+		for i, cat := range cats {
+			if cat.ID == id {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, repository.ErrInvalidCursor
+		}
+	}
+	if start > len(cats) {
+		start = len(cats)
+	}
+
+	end := start + filter.Limit
+	if end < len(cats) {
+		list.NextCursor = encodeCursor(filter.Sort, filter.Order, cats[end-1].ID)
+	} else {
+		end = len(cats)
+	}
+	list.Items = append(list.Items, cats[start:end]...)
+
+	return list, nil
+}
+
+// Search поиск подстроки без учета регистра. Морфология и опечатки не учитываются
+func (r *CatRepository) Search(ctx context.Context, q string, limit int) (*[]entities.CatSearchResult, error) {
+	q = strings.ToLower(q)
+
+	r.s.mu.RLock()
+	results := []entities.CatSearchResult{}
+	for _, cat := range r.s.cats {
+		text := cat.Breed + " — " + cat.Fur + ", " + cat.Temper
+		if strings.Contains(strings.ToLower(text), q) {
+			results = append(results, entities.CatSearchResult{Cat: cat, Rank: 1, Snippet: html.EscapeString(text)})
+		}
+	}
+	r.s.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID < results[j].ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return &results, nil
+}
+
+// catLess функции сравнения для допустимых полей сортировки
+var catLess = map[string]func(a, b entities.Cat) bool{
+	"id":              func(a, b entities.Cat) bool { return a.ID < b.ID },
+	"breed":           func(a, b entities.Cat) bool { return a.Breed < b.Breed },
+	"fur":             func(a, b entities.Cat) bool { return a.Fur < b.Fur },
+	"temper":          func(a, b entities.Cat) bool { return a.Temper < b.Temper },
+	"care_complexity": func(a, b entities.Cat) bool { return a.CareComplexity < b.CareComplexity },
+}
+
+func catMatches(cat entities.Cat, filter *entities.CatFilter) bool {
+	if filter.Fur != "" && cat.Fur != filter.Fur {
+		return false
+	}
+	if filter.Temper != "" && cat.Temper != filter.Temper {
+		return false
+	}
+	if filter.Breed != "" && !strings.Contains(strings.ToLower(cat.Breed), strings.ToLower(filter.Breed)) {
+		return false
+	}
+	if filter.CareComplexityMin > 0 && cat.CareComplexity < filter.CareComplexityMin {
+		return false
+	}
+	if filter.CareComplexityMax > 0 && cat.CareComplexity > filter.CareComplexityMax {
+		return false
+	}
+	return true
+}
+
+func encodeCursor(sortField, order string, id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s:%d", sortField, order, id)))
+}
+
+func decodeCursor(s string) (string, string, int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return "", "", 0, err
+	}
+	parts := strings.Split(string(data), ":")
+	if len(parts) != 3 {
+		return "", "", 0, repository.ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return "", "", 0, err
+	}
+	return parts[0], parts[1], id, nil
+}
+
+// UserRepository Реализация repository.UserRepository в памяти
+type UserRepository struct {
+	s *store
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *entities.User) (*entities.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, u := range r.s.users {
+		if u.Email == user.Email {
+			return nil, apperror.Conflict("user_exists", "user already exists")
+		}
+	}
+	user.ID = r.s.nextID()
+	r.s.users[user.ID] = *user
+	return user, nil
+}
+
+func (r *UserRepository) Exists(ctx context.Context, email string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, u := range r.s.users {
+		if u.Email == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *UserRepository) ExistsID(ctx context.Context, id int) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	_, ok := r.s.users[id]
+	return ok, nil
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*entities.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, u := range r.s.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, apperror.NotFound("user_not_found", "user not found")
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*entities.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	u, ok := r.s.users[id]
+	if !ok {
+		return nil, apperror.NotFound("user_not_found", "user not found")
+	}
+	return &u, nil
+}
+
+func (r *UserRepository) GetDataByID(ctx context.Context, id int) (*entities.UserData, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	u, ok := r.s.users[id]
+	if !ok {
+		return nil, apperror.NotFound("user_not_found", "user not found")
+	}
+	return &entities.UserData{ID: u.ID, Email: u.Email, Name: u.Name, Surname: u.Surname}, nil
+}
+
+func (r *UserRepository) GetRoleByID(ctx context.Context, id int) (string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	u, ok := r.s.users[id]
+	if !ok {
+		return "", apperror.NotFound("user_not_found", "user not found")
+	}
+	return u.Role, nil
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, id int, role string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[id]
+	if !ok {
+		return apperror.NotFound("user_not_found", "user not found")
+	}
+	u.Role = role
+	r.s.users[id] = u
+	return nil
+}
+
+// FavoriteRepository Реализация repository.FavoriteRepository в памяти
+type FavoriteRepository struct {
+	s *store
+}
+
+func (r *FavoriteRepository) GetCats(ctx context.Context, userID int) (*[]entities.FavoriteCat, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var favorites []entities.Favorite
+	for _, fav := range r.s.favorites {
+		if fav.UserID == userID {
+			favorites = append(favorites, fav)
+		}
+	}
+	sort.Slice(favorites, func(i, j int) bool {
+		return favorites[i].ID < favorites[j].ID
+	})
+
+	var cats []entities.FavoriteCat
+	for _, fav := range favorites {
+		cat := r.s.cats[fav.CatID]
+		cats = append(cats, entities.FavoriteCat{
+			ID:        cat.ID,
+			Breed:     cat.Breed,
+			ImagePath: cat.ImagePath,
+			Images:    cat.Images,
+		})
+	}
+	return &cats, nil
+}
+
+func (r *FavoriteRepository) Exists(ctx context.Context, favorite *entities.Favorite) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	_, ok := r.find(favorite)
+	return ok, nil
+}
+
+func (r *FavoriteRepository) Add(ctx context.Context, favorite *entities.Favorite) (*entities.Favorite, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.cats[favorite.CatID]; !ok {
+		return nil, apperror.NotFound("cat_not_found", "cat not found")
+	}
+	favorite.ID = r.s.nextID()
+	r.s.favorites[favorite.ID] = *favorite
+	return favorite, nil
+}
+
+func (r *FavoriteRepository) Remove(ctx context.Context, favorite *entities.Favorite) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if id, ok := r.find(favorite); ok {
+		delete(r.s.favorites, id)
+	}
+	return nil
+}
+
+// find поиск записи избранного по пользователю и кошке, вызывается под блокировкой
+func (r *FavoriteRepository) find(favorite *entities.Favorite) (int, bool) {
+	for id, fav := range r.s.favorites {
+		if fav.UserID == favorite.UserID && fav.CatID == favorite.CatID {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// TokenRepository Реализация repository.TokenRepository в памяти
+type TokenRepository struct {
+	s *store
+}
+
+func (r *TokenRepository) CreateRefresh(ctx context.Context, token *entities.RefreshToken) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	token.ID = r.s.nextID()
+	token.CreatedAt = time.Now()
+	r.s.refreshTokens[token.TokenHash] = *token
+	return nil
+}
+
+func (r *TokenRepository) UseRefresh(ctx context.Context, tokenHash string) (*entities.RefreshToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	token, ok := r.s.refreshTokens[tokenHash]
+	now := time.Now()
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil || !token.ExpiresAt.After(now) {
+		return nil, apperror.NotFound("refresh_token_not_found", "refresh token not found")
+	}
+	token.UsedAt = &now
+	r.s.refreshTokens[tokenHash] = token
+	return &token, nil
+}
+
+func (r *TokenRepository) GetRefreshByHash(ctx context.Context, tokenHash string) (*entities.RefreshToken, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	token, ok := r.s.refreshTokens[tokenHash]
+	if !ok {
+		return nil, apperror.NotFound("refresh_token_not_found", "refresh token not found")
+	}
+	return &token, nil
+}
+
+func (r *TokenRepository) RevokeRefreshFamily(ctx context.Context, familyID string) error {
+	return r.revokeRefresh(func(token entities.RefreshToken) bool { return token.FamilyID == familyID })
+}
+
+func (r *TokenRepository) RevokeRefreshUser(ctx context.Context, userID int) error {
+	return r.revokeRefresh(func(token entities.RefreshToken) bool { return token.UserID == userID })
+}
+
+// revokeRefresh отзыв еще не отозванных рефреш токенов, подходящих под match
+func (r *TokenRepository) revokeRefresh(match func(token entities.RefreshToken) bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	for hash, token := range r.s.refreshTokens {
+		if token.RevokedAt == nil && match(token) {
+			token.RevokedAt = &now
+			r.s.refreshTokens[hash] = token
+		}
+	}
+	return nil
+}
+
+func (r *TokenRepository) RevokeAccess(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.revokedTokens[jti] = expiresAt
+	return nil
+}
+
+func (r *TokenRepository) RevokeAccessUser(ctx context.Context, userID int) (time.Time, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	revokedAt := time.Now()
+	r.s.tokensRevokedAt[userID] = revokedAt
+	return revokedAt, nil
+}
+
+func (r *TokenRepository) GetRevokedAccess(ctx context.Context, maxAge time.Duration) (map[string]time.Time, map[int]time.Time, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	now := time.Now()
+	tokens := make(map[string]time.Time)
+	for jti, expiresAt := range r.s.revokedTokens {
+		if expiresAt.After(now) {
+			tokens[jti] = expiresAt
+		}
+	}
+	users := make(map[int]time.Time)
+	for userID, revokedAt := range r.s.tokensRevokedAt {
+		if revokedAt.After(now.Add(-maxAge)) {
+			users[userID] = revokedAt
+		}
+	}
+	return tokens, users, nil
+}
+
+func (r *TokenRepository) CleanupRevokedAccess(ctx context.Context) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	for jti, expiresAt := range r.s.revokedTokens {
+		if !expiresAt.After(now) {
+			delete(r.s.revokedTokens, jti)
+		}
+	}
+	return nil
+}
+
+// EmailVerificationRepository Реализация repository.EmailVerificationRepository в памяти
+type EmailVerificationRepository struct {
+	s *store
+}
+
+func (r *EmailVerificationRepository) Create(ctx context.Context, tokenHash string, userID int, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.verifications[tokenHash] = verificationToken{userID: userID, expiresAt: expiresAt}
+	return nil
+}
+
+// Use отметка токена как использованного и подтверждение email владельца под одной блокировкой,
+// как в одном запросе к бд
+func (r *EmailVerificationRepository) Use(ctx context.Context, tokenHash string, userID int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	token, ok := r.s.verifications[tokenHash]
+	now := time.Now()
+	if !ok || token.userID != userID || token.used || !token.expiresAt.After(now) {
+		return apperror.NotFound("verification_token_not_found", "verification token not found")
+	}
+	token.used = true
+	r.s.verifications[tokenHash] = token
+
+	user, ok := r.s.users[token.userID]
+	if !ok {
+		return apperror.NotFound("verification_token_not_found", "verification token not found")
+	}
+	if user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &now
+		r.s.users[user.ID] = user
+	}
+	return nil
+}
+
+func (r *EmailVerificationRepository) Cleanup(ctx context.Context) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	for hash, token := range r.s.verifications {
+		if token.used || !token.expiresAt.After(now) {
+			delete(r.s.verifications, hash)
+		}
+	}
+	return nil
+}
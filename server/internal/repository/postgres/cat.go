@@ -1,23 +1,28 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/jmoiron/sqlx"
 	"server/internal/entities"
 )
 
-func DBCatCreate(db *sqlx.DB, cat *entities.Cat) (*entities.Cat, error) {
+// catColumns колонки таблицы cats, соответствующие entities.Cat.
+// Служебные колонки (например, search_vector) в выборку не попадают
+const catColumns = `id, breed, fur, temper, care_complexity, image_path, images`
+
+func DBCatCreate(ctx context.Context, db *sqlx.DB, cat *entities.Cat) (*entities.Cat, error) {
 	query := `
-		INSERT INTO cats (breed, fur, temper, care_complexity, image_path)
-		VALUES (:breed, :fur, :temper, :care_complexity, :image_path) RETURNING id
+		INSERT INTO cats (breed, fur, temper, care_complexity, image_path, images)
+		VALUES (:breed, :fur, :temper, :care_complexity, :image_path, :images) RETURNING id
 	`
 
-	stmt, err := db.PrepareNamed(query)
+	stmt, err := db.PrepareNamedContext(ctx, query)
 	if stmt == nil {
 		return nil, err
 	}
-	err = stmt.Get(&cat.ID, cat)
+	err = stmt.GetContext(ctx, &cat.ID, cat)
 	if err != nil {
 		return nil, err
 	}
@@ -25,11 +30,11 @@ func DBCatCreate(db *sqlx.DB, cat *entities.Cat) (*entities.Cat, error) {
 	return cat, nil
 }
 
-func DBCatExistsID(db *sqlx.DB, catID int) (bool, error) {
+func DBCatExistsID(ctx context.Context, db *sqlx.DB, catID int) (bool, error) {
 	exists := 0
 	query := `SELECT 1 FROM cats WHERE id = $1 LIMIT 1`
 
-	err := db.QueryRow(query, catID).Scan(&exists)
+	err := db.QueryRowContext(ctx, query, catID).Scan(&exists)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return false, err
 	}
@@ -39,11 +44,11 @@ func DBCatExistsID(db *sqlx.DB, catID int) (bool, error) {
 	return false, nil
 }
 
-func DBCatExistsBreed(db *sqlx.DB, breed string) (bool, error) {
+func DBCatExistsBreed(ctx context.Context, db *sqlx.DB, breed string) (bool, error) {
 	exists := 0
 	query := `SELECT 1 FROM cats WHERE breed = $1 LIMIT 1`
 
-	err := db.QueryRow(query, breed).Scan(&exists)
+	err := db.QueryRowContext(ctx, query, breed).Scan(&exists)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return false, err
 	}
@@ -53,42 +58,52 @@ func DBCatExistsBreed(db *sqlx.DB, breed string) (bool, error) {
 	return false, nil
 }
 
-func DBCatUpdate(db *sqlx.DB, cat *entities.UpdateCatRequest) error {
+func DBCatUpdate(ctx context.Context, db *sqlx.DB, cat *entities.UpdateCatRequest) error {
 	query := `UPDATE cats SET breed = $1, fur = $2, temper = $3, care_complexity = $4 WHERE id = $5`
-	_, err := db.Exec(query, cat.Breed, cat.Fur, cat.Temper, cat.CareComplexity, cat.ID)
+	res, err := db.ExecContext(ctx, query, cat.Breed, cat.Fur, cat.Temper, cat.CareComplexity, cat.ID)
 	if err != nil {
 		return err
 	}
-	return nil
+	return requireAffected(res, "cat_not_found", "cat not found")
 }
 
-func DBCatDelete(db *sqlx.DB, catID int) error {
+func DBCatDelete(ctx context.Context, db *sqlx.DB, catID int) error {
 	query := `DELETE FROM cats WHERE id = $1`
-	_, err := db.Exec(query, catID)
+	res, err := db.ExecContext(ctx, query, catID)
 	if err != nil {
 		return err
 	}
-	return nil
+	return requireAffected(res, "cat_not_found", "cat not found")
 }
 
-func DBCatGetByID(db *sqlx.DB, catID int) (*entities.Cat, error) {
+func DBCatGetByID(ctx context.Context, db *sqlx.DB, catID int) (*entities.Cat, error) {
 	cat := entities.Cat{}
-	query := `SELECT * FROM cats WHERE id = $1
-	`
+	query := `SELECT ` + catColumns + ` FROM cats WHERE id = $1`
 
-	err := db.Get(&cat, query, catID)
+	err := db.GetContext(ctx, &cat, query, catID)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "cat_not_found", "cat not found")
 	}
 	return &cat, nil
 }
 
-func DBCatGetAll(db *sqlx.DB) (*[]entities.Cat, error) {
+// DBCatImagesGetAll получение путей к изображениям всех котов, используется при переносе файлов между хранилищами
+func DBCatImagesGetAll(ctx context.Context, db *sqlx.DB) (*[]entities.Cat, error) {
 	var cats []entities.Cat
-	query := `SELECT * FROM cats ORDER BY id`
-	err := db.Select(&cats, query)
+	query := `SELECT ` + catColumns + ` FROM cats ORDER BY id`
+
+	err := db.SelectContext(ctx, &cats, query)
 	if err != nil {
 		return nil, err
 	}
+
 	return &cats, nil
 }
+
+// DBCatImagesUpdate обновление ключей изображений кота
+func DBCatImagesUpdate(ctx context.Context, db *sqlx.DB, catID int, imagePath string, images entities.CatImages) error {
+	query := `UPDATE cats SET image_path = $1, images = $2 WHERE id = $3`
+
+	_, err := db.ExecContext(ctx, query, imagePath, images, catID)
+	return err
+}
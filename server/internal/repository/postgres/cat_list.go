@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"server/internal/entities"
+	"server/internal/repository"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidCursor курсор пагинации не удалось разобрать
+	ErrInvalidCursor = repository.ErrInvalidCursor
+	// ErrInvalidSort неизвестное поле сортировки
+	ErrInvalidSort = repository.ErrInvalidSort
+)
+
+// catSortColumns допустимые поля сортировки и соответствующие им колонки
+var catSortColumns = map[string]string{
+	"id":              "id",
+	"breed":           "breed",
+	"fur":             "fur",
+	"temper":          "temper",
+	"care_complexity": "care_complexity",
+}
+
+// catCursor позиция последней отданной записи: поле и направление сортировки, значение поля и id
+type catCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// DBCatList получение страницы списка кошек с фильтрацией и сортировкой.
+// Если передан курсор, используется пагинация по ключу, иначе по смещению
+func DBCatList(ctx context.Context, db *sqlx.DB, filter *entities.CatFilter) (*entities.CatList, error) {
+	column, ok := catSortColumns[filter.Sort]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+	desc := filter.Order == "desc"
+
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.Fur != "" {
+		where = append(where, "fur = "+arg(filter.Fur))
+	}
+	if filter.Temper != "" {
+		where = append(where, "temper = "+arg(filter.Temper))
+	}
+	if filter.Breed != "" {
+		where = append(where, "breed ILIKE "+arg("%"+escapeLike(filter.Breed)+"%"))
+	}
+	if filter.CareComplexityMin > 0 {
+		where = append(where, "care_complexity >= "+arg(filter.CareComplexityMin))
+	}
+	if filter.CareComplexityMax > 0 {
+		where = append(where, "care_complexity <= "+arg(filter.CareComplexityMax))
+	}
+
+	list := &entities.CatList{Items: []entities.Cat{}}
+
+	countQuery := `SELECT count(*) FROM cats` + whereClause(where)
+	if err := db.GetContext(ctx, &list.Total, countQuery, args...); err != nil {
+		return nil, err
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeCatCursor(filter.Cursor)
+		if err != nil || cursor.Sort != column || cursor.Order != filter.Order {
+			return nil, ErrInvalidCursor
+		}
+
+		cmp := ">"
+		if desc {
+			cmp = "<"
+		}
+		if column == "id" {
+			where = append(where, fmt.Sprintf("id %s %s", cmp, arg(cursor.ID)))
+		} else {
+			var value interface{} = cursor.Value
+			if column == "care_complexity" {
+				n, err := strconv.Atoi(cursor.Value)
+				if err != nil {
+					return nil, ErrInvalidCursor
+				}
+				value = n
+			}
+			where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)", column, cmp, arg(value), arg(cursor.ID)))
+		}
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	orderBy := fmt.Sprintf(" ORDER BY %s %s", column, direction)
+	if column != "id" {
+		orderBy += ", id " + direction
+	}
+
+	// Запрашивается на одну запись больше, чтобы узнать, есть ли следующая страница
+	query := `SELECT ` + catColumns + ` FROM cats` + whereClause(where) + orderBy + " LIMIT " + arg(filter.Limit+1)
+	if filter.Cursor == "" && filter.Offset > 0 {
+		query += " OFFSET " + arg(filter.Offset)
+	}
+
+	if err := db.SelectContext(ctx, &list.Items, query, args...); err != nil {
+		return nil, err
+	}
+
+	if len(list.Items) > filter.Limit {
+		list.Items = list.Items[:filter.Limit]
+		list.NextCursor = encodeCatCursor(list.Items[len(list.Items)-1], column, filter.Order)
+	}
+
+	return list, nil
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// escapeLike экранирование спецсимволов шаблона LIKE
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func encodeCatCursor(cat entities.Cat, column, order string) string {
+	cursor := catCursor{Sort: column, Order: order, ID: cat.ID}
+	switch column {
+	case "breed":
+		cursor.Value = cat.Breed
+	case "fur":
+		cursor.Value = cat.Fur
+	case "temper":
+		cursor.Value = cat.Temper
+	case "care_complexity":
+		cursor.Value = strconv.Itoa(cat.CareComplexity)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCatCursor(s string) (*catCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor catCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"server/internal/entities"
+)
+
+// DBCatSearch полнотекстовый поиск кошек по породе, шерсти и темпераменту.
+// Совпадения по словоформам (конфигурация russian) дополняются нечетким поиском по триграммам,
+// чтобы находить породы с опечатками. Результаты упорядочены по релевантности
+func DBCatSearch(ctx context.Context, db *sqlx.DB, q string, limit int) (*[]entities.CatSearchResult, error) {
+	results := []entities.CatSearchResult{}
+	query := `
+	WITH q AS (SELECT websearch_to_tsquery('russian', $1) AS tsq)
+	SELECT ` + catColumns + `, rank,
+		ts_headline('russian', ` + htmlEscape(`breed || ' — ' || fur || ', ' || temper`) + `, q.tsq,
+			'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS snippet
+	FROM (
+		SELECT cats.*,
+			ts_rank_cd(search_vector, q.tsq) +
+			greatest(similarity(breed, $1), similarity(fur, $1), similarity(temper, $1)) AS rank
+		FROM cats, q
+		WHERE search_vector @@ q.tsq OR breed % $1 OR fur % $1 OR temper % $1
+	) AS found, q
+	ORDER BY rank DESC, id
+	LIMIT $2`
+
+	err := db.SelectContext(ctx, &results, query, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+// htmlEscape Выражение SQL, экранирующее спецсимволы HTML в expr, как html.EscapeString.
+// snippet выводится клиентами как разметка, поэтому в нем не должно быть тегов, кроме mark.
+// Парсер полнотекстового поиска пропускает сущности HTML, поэтому совпадения слов не меняются
+func htmlEscape(expr string) string {
+	return `replace(replace(replace(replace(replace(` + expr +
+		`, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;')`
+}
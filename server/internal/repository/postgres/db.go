@@ -1,43 +1,37 @@
 package postgres
 
 import (
+	"database/sql"
 	"fmt"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
-	"log"
+	"github.com/lib/pq"
+	"net/url"
 	"server/internal/config"
 )
 
 // NewDatabase инициализация подключения к бд
-func NewDatabase() (*sqlx.DB, error) {
-	connectionString := fmt.Sprintf(
-		"postgres://%v:%v@%v:%v/%v?sslmode=disable",
-		config.DBUser,
-		config.DBPassword,
-		config.DBHost,
-		config.DBPort,
-		config.DBName,
-	)
+func NewDatabase(cfg config.PostgresConfig) (*sqlx.DB, error) {
+	connectionURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     fmt.Sprintf("%v:%v", cfg.Host, cfg.Port),
+		Path:     cfg.Name,
+		RawQuery: url.Values{"sslmode": {cfg.SSLMode}}.Encode(),
+	}
+	connectionString := connectionURL.String()
 
-	db, err := sqlx.Connect("postgres", connectionString)
+	connector, err := pq.NewConnector(connectionString)
 	if err != nil {
-		log.Fatalf("Error opening database connection: %v", err)
+		return nil, fmt.Errorf("error opening database connection: %w", err)
 	}
+	// Каждый запрос к бд в рамках трассы записывается отдельным спаном
+	db := sqlx.NewDb(sql.OpenDB(tracedConnector{connector}), "postgres")
 
 	err = db.Ping()
 	if err != nil {
-		log.Fatalf("Error pinging database connection: %v", err)
+		_ = db.Close()
+		return nil, fmt.Errorf("error pinging database connection: %w", err)
 	}
 
-	fmt.Println("Successfully connected to database. Creating tables")
-	CreateTable(db)
-
 	return db, nil
 }
-
-// CreateTable cоздание всех таблиц
-func CreateTable(db *sqlx.DB) {
-	db.MustExec(createUserTable)
-	db.MustExec(createCatTable)
-	db.MustExec(createFavoritesTable)
-}
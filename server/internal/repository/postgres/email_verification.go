@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// DBEmailVerificationTokenCreate сохранение хэша токена подтверждения email
+func DBEmailVerificationTokenCreate(ctx context.Context, db *sqlx.DB, tokenHash string, userID int, expiresAt time.Time) error {
+	query := `INSERT INTO email_verification_tokens (token_hash, user_id, expires_at) VALUES ($1, $2, $3)`
+	_, err := db.ExecContext(ctx, query, tokenHash, userID, expiresAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DBEmailVerificationTokenUse атомарная отметка токена пользователя userID как использованного и
+// подтверждение его email. Возвращает apperror.ErrNotFound, если токен не найден, уже использован,
+// истек или выдан другому пользователю
+func DBEmailVerificationTokenUse(ctx context.Context, db *sqlx.DB, tokenHash string, userID int) error {
+	var verifiedID int
+	query := `WITH used AS (
+		UPDATE email_verification_tokens SET used_at = now()
+		WHERE token_hash = $1 AND user_id = $2 AND used_at IS NULL AND expires_at > now()
+		RETURNING user_id
+	)
+	UPDATE users SET email_verified_at = COALESCE(email_verified_at, now())
+	FROM used WHERE users.id = used.user_id
+	RETURNING users.id`
+
+	err := db.GetContext(ctx, &verifiedID, query, tokenHash, userID)
+	if err != nil {
+		return notFound(err, "verification_token_not_found", "verification token not found")
+	}
+	return nil
+}
+
+// DBEmailVerificationTokensCleanup удаление истекших и использованных токенов подтверждения email
+func DBEmailVerificationTokensCleanup(ctx context.Context, db *sqlx.DB) error {
+	query := `DELETE FROM email_verification_tokens WHERE expires_at <= now() OR used_at IS NOT NULL`
+	_, err := db.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	return nil
+}
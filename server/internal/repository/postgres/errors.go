@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"server/internal/apperror"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// IsTimeout Запрос прерван по истечении времени: дедлайн контекста или statement_timeout сервера
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	// 57014 query_canceled: pq отменяет запрос на сервере при отмене контекста
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
+
+// IsUnavailable Бд недоступна: нет соединения, сервер перезапускается или исчерпан лимит
+// подключений. Сюда же относится отмена контекста, например при остановке сервера
+func IsUnavailable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		code := string(pqErr.Code)
+		// 08 connection_exception, 53300 too_many_connections, 57P01-57P03 остановка и запуск сервера
+		return strings.HasPrefix(code, "08") || code == "53300" || strings.HasPrefix(code, "57P")
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// notFound Замена sql.ErrNoRows на apperror.ErrNotFound с кодом code и сообщением message,
+// остальные ошибки не меняются
+func notFound(err error, code, message string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperror.Wrap(apperror.ErrNotFound, code, message, err)
+	}
+	return err
+}
+
+// requireAffected apperror.ErrNotFound, если запрос не изменил ни одной строки
+func requireAffected(res sql.Result, code, message string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return apperror.NotFound(code, message)
+	}
+	return nil
+}
+
+// isUniqueViolation Нарушение ограничения уникальности (23505 unique_violation)
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isForeignKeyViolation Ссылка на несуществующую запись (23503 foreign_key_violation)
+// через внешний ключ constraint
+func isForeignKeyViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503" && pqErr.Constraint == constraint
+}
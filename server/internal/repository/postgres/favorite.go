@@ -1,21 +1,23 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/jmoiron/sqlx"
+	"server/internal/apperror"
 	"server/internal/entities"
 )
 
-func DBGetFavoriteCats(db *sqlx.DB, userID int) (*[]entities.FavoriteCat, error) {
+func DBGetFavoriteCats(ctx context.Context, db *sqlx.DB, userID int) (*[]entities.FavoriteCat, error) {
 	var favorites []entities.FavoriteCat
 
 	query := `
-	SELECT cats.id, cats.breed, cats.image_path FROM favorites
+	SELECT cats.id, cats.breed, cats.image_path, cats.images FROM favorites
 	JOIN cats ON favorites.cat_id = cats.id
 	WHERE favorites.user_id = $1;`
 
-	err := db.Select(&favorites, query, userID)
+	err := db.SelectContext(ctx, &favorites, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -23,11 +25,11 @@ func DBGetFavoriteCats(db *sqlx.DB, userID int) (*[]entities.FavoriteCat, error)
 
 }
 
-func DBFavoriteExists(db *sqlx.DB, favorite *entities.Favorite) (bool, error) {
+func DBFavoriteExists(ctx context.Context, db *sqlx.DB, favorite *entities.Favorite) (bool, error) {
 	exists := 0
 	query := `SELECT 1 FROM favorites WHERE user_id = $1 AND cat_id = $2 LIMIT 1`
 
-	err := db.QueryRow(query, favorite.UserID, favorite.CatID).Scan(&exists)
+	err := db.QueryRowContext(ctx, query, favorite.UserID, favorite.CatID).Scan(&exists)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return false, err
 	}
@@ -37,19 +39,24 @@ func DBFavoriteExists(db *sqlx.DB, favorite *entities.Favorite) (bool, error) {
 	return false, nil
 }
 
-func DBAddFavoriteCat(db *sqlx.DB, fav *entities.Favorite) (*entities.Favorite, error) {
+func DBAddFavoriteCat(ctx context.Context, db *sqlx.DB, fav *entities.Favorite) (*entities.Favorite, error) {
 	query := `INSERT INTO favorites (user_id, cat_id) VALUES ($1, $2) RETURNING id;`
 
-	err := db.QueryRow(query, fav.UserID, fav.CatID).Scan(&fav.ID)
+	err := db.QueryRowContext(ctx, query, fav.UserID, fav.CatID).Scan(&fav.ID)
+	// Кошку могли удалить между проверкой в сервисе и вставкой. Нарушение других ключей,
+	// например удаленный пользователь, остается внутренней ошибкой
+	if isForeignKeyViolation(err, "favorites_cat_id_fkey") {
+		return nil, apperror.Wrap(apperror.ErrNotFound, "cat_not_found", "cat not found", err)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return fav, nil
 }
 
-func DBRemoveFavoriteCat(db *sqlx.DB, fav *entities.Favorite) error {
+func DBRemoveFavoriteCat(ctx context.Context, db *sqlx.DB, fav *entities.Favorite) error {
 	query := `DELETE FROM favorites WHERE user_id = $1 and cat_id = $2;`
-	_, err := db.Exec(query, fav.UserID, fav.CatID)
+	_, err := db.ExecContext(ctx, query, fav.UserID, fav.CatID)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS favorites;
+DROP TABLE IF EXISTS cats;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS third_name VARCHAR NOT NULL DEFAULT '';
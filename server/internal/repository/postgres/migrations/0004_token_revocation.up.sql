@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+    jti VARCHAR PRIMARY KEY,
+    user_id INTEGER NOT NULL references users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS revoked_access_tokens_expires_at_idx ON revoked_access_tokens (expires_at);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_revoked_at TIMESTAMPTZ;
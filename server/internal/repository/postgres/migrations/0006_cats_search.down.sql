@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS cats_temper_trgm_idx;
+DROP INDEX IF EXISTS cats_fur_trgm_idx;
+DROP INDEX IF EXISTS cats_breed_trgm_idx;
+DROP INDEX IF EXISTS cats_search_vector_idx;
+
+ALTER TABLE cats DROP COLUMN IF EXISTS search_vector;
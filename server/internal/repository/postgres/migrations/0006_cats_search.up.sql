@@ -0,0 +1,13 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE cats ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', coalesce(breed, '')), 'A') ||
+        setweight(to_tsvector('russian', coalesce(fur, '')), 'B') ||
+        setweight(to_tsvector('russian', coalesce(temper, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS cats_search_vector_idx ON cats USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS cats_breed_trgm_idx ON cats USING GIN (breed gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS cats_fur_trgm_idx ON cats USING GIN (fur gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS cats_temper_trgm_idx ON cats USING GIN (temper gin_trgm_ops);
@@ -0,0 +1 @@
+ALTER TABLE cats ADD COLUMN IF NOT EXISTS images JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,286 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"server/internal/entities"
+	"server/internal/repository"
+	"time"
+)
+
+// NewRepositories Хранилища приложения поверх PostgreSQL. Каждый запрос к бд ограничен
+// по времени queryTimeout, а также отменяется вместе с контекстом вызывающего
+func NewRepositories(db *sqlx.DB, queryTimeout time.Duration) repository.Repositories {
+	c := conn{db: db, timeout: queryTimeout}
+
+	return repository.Repositories{
+		Cats:               &CatRepository{conn: c},
+		Users:              &UserRepository{conn: c},
+		Favorites:          &FavoriteRepository{conn: c},
+		Tokens:             &TokenRepository{conn: c},
+		EmailVerifications: &EmailVerificationRepository{conn: c},
+	}
+}
+
+// conn Подключение к бд и ограничение времени запроса, общие для всех хранилищ
+type conn struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+// withTimeout Контекст запроса с дедлайном. Если у ctx уже есть более ранний дедлайн, действует он
+func (c conn) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// CatRepository Реализация repository.CatRepository
+type CatRepository struct {
+	conn
+}
+
+func (r *CatRepository) Create(ctx context.Context, cat *entities.Cat) (*entities.Cat, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBCatCreate(ctx, r.db, cat)
+}
+
+func (r *CatRepository) ExistsID(ctx context.Context, id int) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBCatExistsID(ctx, r.db, id)
+}
+
+func (r *CatRepository) ExistsBreed(ctx context.Context, breed string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBCatExistsBreed(ctx, r.db, breed)
+}
+
+func (r *CatRepository) Update(ctx context.Context, cat *entities.UpdateCatRequest) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBCatUpdate(ctx, r.db, cat)
+}
+
+func (r *CatRepository) Delete(ctx context.Context, id int) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBCatDelete(ctx, r.db, id)
+}
+
+func (r *CatRepository) GetByID(ctx context.Context, id int) (*entities.Cat, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBCatGetByID(ctx, r.db, id)
+}
+
+func (r *CatRepository) List(ctx context.Context, filter *entities.CatFilter) (*entities.CatList, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBCatList(ctx, r.db, filter)
+}
+
+func (r *CatRepository) Search(ctx context.Context, q string, limit int) (*[]entities.CatSearchResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBCatSearch(ctx, r.db, q, limit)
+}
+
+// UserRepository Реализация repository.UserRepository
+type UserRepository struct {
+	conn
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *entities.User) (*entities.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBUserCreate(ctx, r.db, user)
+}
+
+func (r *UserRepository) Exists(ctx context.Context, email string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBUserExists(ctx, r.db, email)
+}
+
+func (r *UserRepository) ExistsID(ctx context.Context, id int) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBUserExistsID(ctx, r.db, int64(id))
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*entities.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBUserGetByEmail(ctx, r.db, email)
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*entities.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBUserGetById(ctx, r.db, int64(id))
+}
+
+func (r *UserRepository) GetDataByID(ctx context.Context, id int) (*entities.UserData, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBUserDataGetById(ctx, r.db, int64(id))
+}
+
+func (r *UserRepository) GetRoleByID(ctx context.Context, id int) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBUserRoleGetById(ctx, r.db, id)
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, id int, role string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBUserRoleUpdate(ctx, r.db, id, role)
+}
+
+// FavoriteRepository Реализация repository.FavoriteRepository
+type FavoriteRepository struct {
+	conn
+}
+
+func (r *FavoriteRepository) GetCats(ctx context.Context, userID int) (*[]entities.FavoriteCat, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBGetFavoriteCats(ctx, r.db, userID)
+}
+
+func (r *FavoriteRepository) Exists(ctx context.Context, favorite *entities.Favorite) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBFavoriteExists(ctx, r.db, favorite)
+}
+
+func (r *FavoriteRepository) Add(ctx context.Context, favorite *entities.Favorite) (*entities.Favorite, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBAddFavoriteCat(ctx, r.db, favorite)
+}
+
+func (r *FavoriteRepository) Remove(ctx context.Context, favorite *entities.Favorite) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBRemoveFavoriteCat(ctx, r.db, favorite)
+}
+
+// TokenRepository Реализация repository.TokenRepository
+type TokenRepository struct {
+	conn
+}
+
+func (r *TokenRepository) CreateRefresh(ctx context.Context, token *entities.RefreshToken) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBRefreshTokenCreate(ctx, r.db, token)
+}
+
+func (r *TokenRepository) UseRefresh(ctx context.Context, tokenHash string) (*entities.RefreshToken, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBRefreshTokenUse(ctx, r.db, tokenHash)
+}
+
+func (r *TokenRepository) GetRefreshByHash(ctx context.Context, tokenHash string) (*entities.RefreshToken, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBRefreshTokenGetByHash(ctx, r.db, tokenHash)
+}
+
+func (r *TokenRepository) RevokeRefreshFamily(ctx context.Context, familyID string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBRefreshTokenRevokeFamily(ctx, r.db, familyID)
+}
+
+func (r *TokenRepository) RevokeRefreshUser(ctx context.Context, userID int) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBRefreshTokenRevokeUser(ctx, r.db, userID)
+}
+
+func (r *TokenRepository) RevokeAccess(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBAccessTokenRevoke(ctx, r.db, jti, userID, expiresAt)
+}
+
+func (r *TokenRepository) RevokeAccessUser(ctx context.Context, userID int) (time.Time, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBAccessTokenRevokeUser(ctx, r.db, userID)
+}
+
+func (r *TokenRepository) GetRevokedAccess(ctx context.Context, maxAge time.Duration) (map[string]time.Time, map[int]time.Time, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBRevokedAccessTokensGet(ctx, r.db, maxAge)
+}
+
+func (r *TokenRepository) CleanupRevokedAccess(ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBRevokedAccessTokensCleanup(ctx, r.db)
+}
+
+// EmailVerificationRepository Реализация repository.EmailVerificationRepository
+type EmailVerificationRepository struct {
+	conn
+}
+
+func (r *EmailVerificationRepository) Create(ctx context.Context, tokenHash string, userID int, expiresAt time.Time) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBEmailVerificationTokenCreate(ctx, r.db, tokenHash, userID, expiresAt)
+}
+
+func (r *EmailVerificationRepository) Use(ctx context.Context, tokenHash string, userID int) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBEmailVerificationTokenUse(ctx, r.db, tokenHash, userID)
+}
+
+func (r *EmailVerificationRepository) Cleanup(ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return DBEmailVerificationTokensCleanup(ctx, r.db)
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"server/internal/entities"
+	"time"
+)
+
+// DBRefreshTokenCreate сохранение рефреш токена
+func DBRefreshTokenCreate(ctx context.Context, db *sqlx.DB, token *entities.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (token_hash, family_id, user_id, expires_at)
+	VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+
+	return db.QueryRowContext(ctx, query, token.TokenHash, token.FamilyID, token.UserID, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+}
+
+// DBRefreshTokenUse атомарная отметка рефреш токена как использованного.
+// Возвращает apperror.ErrNotFound, если токен не найден, уже использован, отозван или истек
+func DBRefreshTokenUse(ctx context.Context, db *sqlx.DB, tokenHash string) (*entities.RefreshToken, error) {
+	token := entities.RefreshToken{}
+	query := `UPDATE refresh_tokens SET used_at = now()
+	WHERE token_hash = $1 AND used_at IS NULL AND revoked_at IS NULL AND expires_at > now()
+	RETURNING *`
+
+	err := db.GetContext(ctx, &token, query, tokenHash)
+	if err != nil {
+		return nil, notFound(err, "refresh_token_not_found", "refresh token not found")
+	}
+	return &token, nil
+}
+
+// DBRefreshTokenGetByHash получение рефреш токена по хэшу
+func DBRefreshTokenGetByHash(ctx context.Context, db *sqlx.DB, tokenHash string) (*entities.RefreshToken, error) {
+	token := entities.RefreshToken{}
+	query := `SELECT * FROM refresh_tokens WHERE token_hash = $1`
+
+	err := db.GetContext(ctx, &token, query, tokenHash)
+	if err != nil {
+		return nil, notFound(err, "refresh_token_not_found", "refresh token not found")
+	}
+	return &token, nil
+}
+
+// DBRefreshTokenRevokeFamily отзыв всех токенов цепочки ротаций
+func DBRefreshTokenRevokeFamily(ctx context.Context, db *sqlx.DB, familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL`
+	_, err := db.ExecContext(ctx, query, familyID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DBRefreshTokenRevokeUser отзыв всех рефреш токенов пользователя
+func DBRefreshTokenRevokeUser(ctx context.Context, db *sqlx.DB, userID int) error {
+	query := `UPDATE refresh_tokens SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DBAccessTokenRevoke добавление аксес токена в список отозванных
+func DBAccessTokenRevoke(ctx context.Context, db *sqlx.DB, jti string, userID int, expiresAt time.Time) error {
+	query := `INSERT INTO revoked_access_tokens (jti, user_id, expires_at) VALUES ($1, $2, $3)
+	ON CONFLICT (jti) DO NOTHING`
+	_, err := db.ExecContext(ctx, query, jti, userID, expiresAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DBAccessTokenRevokeUser отзыв всех аксес токенов пользователя, выпущенных до текущего момента
+// Время отзыва берется по часам бэкенда, как и время выпуска токенов, а не по часам бд
+func DBAccessTokenRevokeUser(ctx context.Context, db *sqlx.DB, userID int) (time.Time, error) {
+	revokedAt := time.Now().Truncate(time.Microsecond)
+	query := `UPDATE users SET tokens_revoked_at = $2 WHERE id = $1`
+
+	_, err := db.ExecContext(ctx, query, userID, revokedAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return revokedAt, nil
+}
+
+// DBRevokedAccessTokensGet получение действующих отзывов: отдельных токенов и отзывов всех токенов пользователя.
+// Отзывы всех токенов старше maxAge, максимального срока жизни аксес токена, уже ни на что не влияют
+func DBRevokedAccessTokensGet(ctx context.Context, db *sqlx.DB, maxAge time.Duration) (map[string]time.Time, map[int]time.Time, error) {
+	tokens := make(map[string]time.Time)
+	rows, err := db.QueryContext(ctx, `SELECT jti, expires_at FROM revoked_access_tokens WHERE expires_at > now()`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var jti string
+		var expiresAt time.Time
+		if err := rows.Scan(&jti, &expiresAt); err != nil {
+			return nil, nil, err
+		}
+		tokens[jti] = expiresAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	users := make(map[int]time.Time)
+	rows, err = db.QueryContext(ctx, `SELECT id, tokens_revoked_at FROM users WHERE tokens_revoked_at > $1`,
+		time.Now().Add(-maxAge))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID int
+		var revokedAt time.Time
+		if err := rows.Scan(&userID, &revokedAt); err != nil {
+			return nil, nil, err
+		}
+		users[userID] = revokedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return tokens, users, nil
+}
+
+// DBRevokedAccessTokensCleanup удаление записей об уже истекших отозванных токенах
+func DBRevokedAccessTokensCleanup(ctx context.Context, db *sqlx.DB) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM revoked_access_tokens WHERE expires_at <= now()`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
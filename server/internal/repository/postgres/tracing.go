@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"strings"
+)
+
+// tracerName Имя инструментирующей библиотеки для спанов запросов к бд
+const tracerName = "server/internal/repository/postgres"
+
+// Атрибуты спанов с числом строк
+const (
+	rowsReturnedKey = attribute.Key("db.response.returned_rows")
+	rowsAffectedKey = attribute.Key("db.response.affected_rows")
+)
+
+// pqConn Интерфейсы соединения lib/pq, которые использует database/sql
+type pqConn interface {
+	driver.Conn
+	driver.QueryerContext
+	driver.ExecerContext
+	driver.ConnPrepareContext
+	driver.ConnBeginTx
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// pqStmt Интерфейсы подготовленного запроса lib/pq
+type pqStmt interface {
+	driver.Stmt
+	driver.StmtQueryContext
+	driver.StmtExecContext
+}
+
+// tracedConnector Обертка драйвера, создающая спан на каждый запрос к бд
+type tracedConnector struct {
+	driver.Connector
+}
+
+func (c tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pc, ok := conn.(pqConn); ok {
+		return tracedConn{pc}, nil
+	}
+	return conn, nil
+}
+
+type tracedConn struct {
+	pqConn
+}
+
+func (c tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	rows, err := c.pqConn.QueryContext(ctx, query, args)
+	return tracedRows(span, rows, err)
+}
+
+func (c tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	res, err := c.pqConn.ExecContext(ctx, query, args)
+	return tracedResult(span, res, err)
+}
+
+func (c tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.pqConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if ps, ok := stmt.(pqStmt); ok {
+		return tracedStmt{ps, query}, nil
+	}
+	return stmt, nil
+}
+
+type tracedStmt struct {
+	pqStmt
+	query string
+}
+
+func (s tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := startQuerySpan(ctx, s.query)
+	rows, err := s.pqStmt.QueryContext(ctx, args)
+	return tracedRows(span, rows, err)
+}
+
+func (s tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := startQuerySpan(ctx, s.query)
+	res, err := s.pqStmt.ExecContext(ctx, args)
+	return tracedResult(span, res, err)
+}
+
+// startQuerySpan Спан запроса к бд. Запросы вне трассы (миграции, фоновые задачи) не записываются,
+// чтобы не порождать корневой спан на каждый запрос
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	operation := queryOperation(query)
+	return otel.Tracer(tracerName).Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(strings.TrimSpace(query)),
+		))
+}
+
+// queryOperation Первое слово запроса (SELECT, INSERT, ...), используется как имя спана
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "query"
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// endSpan Завершение спана с ошибкой. ErrSkip - сигнал database/sql, а не ошибка запроса
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func tracedResult(span trace.Span, res driver.Result, err error) (driver.Result, error) {
+	if err == nil {
+		if n, err := res.RowsAffected(); err == nil {
+			span.SetAttributes(rowsAffectedKey.Int64(n))
+		}
+	}
+	endSpan(span, err)
+	return res, err
+}
+
+// tracedRows Спан запроса, возвращающего строки, завершается при закрытии результата,
+// когда известно число прочитанных строк
+func tracedRows(span trace.Span, rows driver.Rows, err error) (driver.Rows, error) {
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	if !span.IsRecording() {
+		return rows, nil
+	}
+	return &countingRows{Rows: rows, span: span}, nil
+}
+
+type countingRows struct {
+	driver.Rows
+	span  trace.Span
+	count int64
+	err   error
+}
+
+func (r *countingRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch {
+	case err == nil:
+		r.count++
+	case !errors.Is(err, io.EOF):
+		r.err = err
+	}
+	return err
+}
+
+func (r *countingRows) Close() error {
+	err := r.Rows.Close()
+	r.span.SetAttributes(rowsReturnedKey.Int64(r.count))
+	endSpan(r.span, errors.Join(r.err, err))
+	return err
+}
@@ -1,43 +1,45 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/jmoiron/sqlx"
+	"server/internal/apperror"
 	"server/internal/entities"
 )
 
 // DBUserGetById получение пользователя по айди
-func DBUserGetById(db *sqlx.DB, id int64) (*entities.User, error) {
+func DBUserGetById(ctx context.Context, db *sqlx.DB, id int64) (*entities.User, error) {
 	user := entities.User{}
-	query := `SELECT id, name, surname, email, password FROM users WHERE id = $1`
-	err := db.Get(&user, query, id)
+	query := `SELECT id, name, surname, email, password, role, email_verified_at FROM users WHERE id = $1`
+	err := db.GetContext(ctx, &user, query, id)
 	if err != nil {
-		return &entities.User{}, nil
+		return nil, notFound(err, "user_not_found", "user not found")
 	}
 
 	return &user, nil
 }
 
 // DBUserDataGetById получение данных пользователя по его айди
-func DBUserDataGetById(db *sqlx.DB, id int64) (*entities.UserData, error) {
+func DBUserDataGetById(ctx context.Context, db *sqlx.DB, id int64) (*entities.UserData, error) {
 	user := entities.UserData{}
 	query := `SELECT id, name, surname, email FROM users WHERE id = $1`
-	err := db.Get(&user, query, id)
+	err := db.GetContext(ctx, &user, query, id)
 	if err != nil {
-		return &entities.UserData{}, nil
+		return nil, notFound(err, "user_not_found", "user not found")
 	}
 
 	return &user, nil
 }
 
 // DBUserGetByEmail получение пользователя по email
-func DBUserGetByEmail(db *sqlx.DB, email string) (*entities.User, error) {
+func DBUserGetByEmail(ctx context.Context, db *sqlx.DB, email string) (*entities.User, error) {
 	user := entities.User{}
-	query := `SELECT id, name, surname, email, password FROM users WHERE email = $1`
-	err := db.Get(&user, query, email)
+	query := `SELECT id, name, surname, email, password, role FROM users WHERE email = $1`
+	err := db.GetContext(ctx, &user, query, email)
 	if err != nil {
-		return &entities.User{}, nil
+		return nil, notFound(err, "user_not_found", "user not found")
 	}
 
 	return &user, nil
@@ -45,11 +47,11 @@ func DBUserGetByEmail(db *sqlx.DB, email string) (*entities.User, error) {
 }
 
 // DBUserExists проверка существования пользователя в бд (по почте)
-func DBUserExists(db *sqlx.DB, email string) (bool, error) {
+func DBUserExists(ctx context.Context, db *sqlx.DB, email string) (bool, error) {
 	exists := 0
 	query := `SELECT 1 FROM users WHERE email = $1 LIMIT 1`
 
-	err := db.QueryRow(query, email).Scan(&exists)
+	err := db.QueryRowContext(ctx, query, email).Scan(&exists)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return false, err
 	}
@@ -60,11 +62,11 @@ func DBUserExists(db *sqlx.DB, email string) (bool, error) {
 }
 
 // DBUserExistsID проверка существования пользователя в бд (по айди)
-func DBUserExistsID(db *sqlx.DB, id int64) (bool, error) {
+func DBUserExistsID(ctx context.Context, db *sqlx.DB, id int64) (bool, error) {
 	exists := 0
 	query := `SELECT 1 FROM users WHERE id = $1 LIMIT 1`
 
-	err := db.QueryRow(query, id).Scan(&exists)
+	err := db.QueryRowContext(ctx, query, id).Scan(&exists)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return false, err
 	}
@@ -75,18 +77,44 @@ func DBUserExistsID(db *sqlx.DB, id int64) (bool, error) {
 }
 
 // DBUserCreate создание пользователя
-func DBUserCreate(db *sqlx.DB, user *entities.User) (*entities.User, error) {
-	query := `INSERT INTO users (email, password, name, surname)
-	VALUES (:email, :password, :name, :surname) RETURNING id`
+func DBUserCreate(ctx context.Context, db *sqlx.DB, user *entities.User) (*entities.User, error) {
+	query := `INSERT INTO users (email, password, name, surname, role)
+	VALUES (:email, :password, :name, :surname, :role) RETURNING id`
 
-	stmt, err := db.PrepareNamed(query)
+	stmt, err := db.PrepareNamedContext(ctx, query)
 	if stmt == nil {
 		return nil, errors.New("error preparing statement")
 	}
-	err = stmt.Get(&user.ID, *user)
+	err = stmt.GetContext(ctx, &user.ID, *user)
+	if isUniqueViolation(err) {
+		return nil, apperror.Wrap(apperror.ErrConflict, "user_exists", "user already exists", err)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
+
+// DBUserRoleGetById получение роли пользователя по айди
+func DBUserRoleGetById(ctx context.Context, db *sqlx.DB, id int) (string, error) {
+	var role string
+	query := `SELECT role FROM users WHERE id = $1`
+	err := db.GetContext(ctx, &role, query, id)
+	if err != nil {
+		return "", notFound(err, "user_not_found", "user not found")
+	}
+
+	return role, nil
+}
+
+// DBUserRoleUpdate изменение роли пользователя
+func DBUserRoleUpdate(ctx context.Context, db *sqlx.DB, id int, role string) error {
+	query := `UPDATE users SET role = $1 WHERE id = $2`
+	res, err := db.ExecContext(ctx, query, role, id)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(res, "user_not_found", "user not found")
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"server/internal/apperror"
+	"server/internal/entities"
+	"time"
+)
+
+var (
+	// ErrInvalidCursor курсор пагинации не удалось разобрать
+	ErrInvalidCursor = apperror.Validation("invalid_cursor", "invalid cursor",
+		apperror.FieldError{Field: "cursor", Code: "invalid", Message: "invalid cursor"})
+	// ErrInvalidSort неизвестное поле сортировки
+	ErrInvalidSort = apperror.Validation("invalid_sort", "invalid sort field",
+		apperror.FieldError{Field: "sort", Code: "invalid", Message: "unknown sort field"})
+)
+
+// Ошибки хранилищ - ошибки из пакета apperror: отсутствующая запись возвращается как
+// apperror.ErrNotFound, нарушение уникальности как apperror.ErrConflict
+
+// CatRepository Хранилище каталога кошек
+type CatRepository interface {
+	Create(ctx context.Context, cat *entities.Cat) (*entities.Cat, error)
+	ExistsID(ctx context.Context, id int) (bool, error)
+	ExistsBreed(ctx context.Context, breed string) (bool, error)
+	// Update, Delete и GetByID возвращают apperror.ErrNotFound, если кошки нет
+	Update(ctx context.Context, cat *entities.UpdateCatRequest) error
+	Delete(ctx context.Context, id int) error
+	GetByID(ctx context.Context, id int) (*entities.Cat, error)
+	// List страница списка кошек, возвращает ErrInvalidSort и ErrInvalidCursor для некорректных параметров
+	List(ctx context.Context, filter *entities.CatFilter) (*entities.CatList, error)
+	Search(ctx context.Context, q string, limit int) (*[]entities.CatSearchResult, error)
+}
+
+// UserRepository Хранилище пользователей
+type UserRepository interface {
+	// Create возвращает apperror.ErrConflict, если email уже занят
+	Create(ctx context.Context, user *entities.User) (*entities.User, error)
+	Exists(ctx context.Context, email string) (bool, error)
+	ExistsID(ctx context.Context, id int) (bool, error)
+	// GetByEmail, GetByID, GetDataByID и GetRoleByID возвращают apperror.ErrNotFound, если пользователя нет
+	GetByEmail(ctx context.Context, email string) (*entities.User, error)
+	GetByID(ctx context.Context, id int) (*entities.User, error)
+	GetDataByID(ctx context.Context, id int) (*entities.UserData, error)
+	GetRoleByID(ctx context.Context, id int) (string, error)
+	UpdateRole(ctx context.Context, id int, role string) error
+}
+
+// FavoriteRepository Хранилище избранных кошек пользователей
+type FavoriteRepository interface {
+	GetCats(ctx context.Context, userID int) (*[]entities.FavoriteCat, error)
+	Exists(ctx context.Context, favorite *entities.Favorite) (bool, error)
+	Add(ctx context.Context, favorite *entities.Favorite) (*entities.Favorite, error)
+	Remove(ctx context.Context, favorite *entities.Favorite) error
+}
+
+// TokenRepository Хранилище рефреш токенов и отзывов аксес токенов
+type TokenRepository interface {
+	CreateRefresh(ctx context.Context, token *entities.RefreshToken) error
+	// UseRefresh атомарная отметка рефреш токена как использованного. Возвращает apperror.ErrNotFound,
+	// если токен не найден, уже использован, отозван или истек
+	UseRefresh(ctx context.Context, tokenHash string) (*entities.RefreshToken, error)
+	// GetRefreshByHash возвращает apperror.ErrNotFound, если токена нет
+	GetRefreshByHash(ctx context.Context, tokenHash string) (*entities.RefreshToken, error)
+	RevokeRefreshFamily(ctx context.Context, familyID string) error
+	RevokeRefreshUser(ctx context.Context, userID int) error
+	RevokeAccess(ctx context.Context, jti string, userID int, expiresAt time.Time) error
+	// RevokeAccessUser отзыв всех аксес токенов пользователя, возвращает время отзыва
+	RevokeAccessUser(ctx context.Context, userID int) (time.Time, error)
+	// GetRevokedAccess действующие отзывы: jti -> время истечения токена и id пользователя -> время
+	// отзыва всех его токенов. Отзывы всех токенов старше maxAge не возвращаются
+	GetRevokedAccess(ctx context.Context, maxAge time.Duration) (map[string]time.Time, map[int]time.Time, error)
+	// CleanupRevokedAccess удаление записей об уже истекших отозванных токенах
+	CleanupRevokedAccess(ctx context.Context) error
+}
+
+// EmailVerificationRepository Хранилище токенов подтверждения email
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, tokenHash string, userID int, expiresAt time.Time) error
+	// Use атомарная отметка токена пользователя userID как использованного и подтверждение его email.
+	// Возвращает apperror.ErrNotFound, если токен не найден, уже использован, истек или выдан другому
+	// пользователю
+	Use(ctx context.Context, tokenHash string, userID int) error
+	// Cleanup удаление истекших и использованных токенов
+	Cleanup(ctx context.Context) error
+}
+
+// Repositories Набор хранилищ приложения
+type Repositories struct {
+	Cats               CatRepository
+	Users              UserRepository
+	Favorites          FavoriteRepository
+	Tokens             TokenRepository
+	EmailVerifications EmailVerificationRepository
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"server/internal/apperror"
+	"server/internal/config"
+	"server/internal/entities"
+	"server/internal/repository"
+	"server/internal/storage"
+	"strings"
+	"unicode/utf8"
+)
+
+// CatService Правила работы с каталогом кошек
+type CatService struct {
+	cats    repository.CatRepository
+	storage storage.Storage
+	images  config.ImagesConfig
+}
+
+// NewCatService Создание сервиса каталога
+func NewCatService(cats repository.CatRepository, st storage.Storage, images config.ImagesConfig) *CatService {
+	return &CatService{cats: cats, storage: st, images: images}
+}
+
+// Create Добавление кошки. Порода должна быть уникальной; изображение проверяется, перекодируется
+// и сохраняется в хранилище только после проверки породы. Ошибки проверки изображения
+// возвращаются из пакета images без изменений
+func (s *CatService) Create(ctx context.Context, cat *entities.Cat, image io.Reader) (*entities.Cat, error) {
+	exists, err := s.cats.ExistsBreed(ctx, cat.Breed)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrCatExists
+	}
+
+	cat.Images, err = storage.SaveCatImages(ctx, s.storage, image, s.images)
+	if err != nil {
+		return nil, err
+	}
+	// image_path хранит ключ полноразмерного изображения в хранилище
+	cat.ImagePath = cat.Images.Full
+
+	return s.cats.Create(ctx, cat)
+}
+
+// Update Изменение данных кошки
+func (s *CatService) Update(ctx context.Context, cat *entities.UpdateCatRequest) error {
+	return catNotFound(s.cats.Update(ctx, cat))
+}
+
+// Delete Удаление кошки
+func (s *CatService) Delete(ctx context.Context, id int) error {
+	return catNotFound(s.cats.Delete(ctx, id))
+}
+
+// GetByID Получение кошки по идентификатору
+func (s *CatService) GetByID(ctx context.Context, id int) (*entities.Cat, error) {
+	cat, err := s.cats.GetByID(ctx, id)
+	if err != nil {
+		return nil, catNotFound(err)
+	}
+	return cat, nil
+}
+
+// catNotFound Замена отсутствия записи в хранилище на ErrCatNotFound
+func catNotFound(err error) error {
+	if errors.Is(err, apperror.ErrNotFound) {
+		return ErrCatNotFound
+	}
+	return err
+}
+
+// List Страница списка кошек. Некорректные параметры, курсор и поле сортировки
+// возвращаются как apperror.ErrValidation
+func (s *CatService) List(ctx context.Context, filter *entities.CatFilter) (*entities.CatList, error) {
+	if fields := validateCatFilter(filter); len(fields) > 0 {
+		return nil, apperror.Validation("invalid_query", "invalid query parameters", fields...)
+	}
+
+	return s.cats.List(ctx, filter)
+}
+
+// validateCatFilter Проверка параметров списка кошек, возвращает ошибки всех некорректных полей
+func validateCatFilter(filter *entities.CatFilter) []apperror.FieldError {
+	var fields []apperror.FieldError
+	if filter.Order != "asc" && filter.Order != "desc" {
+		fields = append(fields, apperror.FieldError{Field: "order", Code: "invalid", Message: "order must be asc or desc"})
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		fields = append(fields, apperror.FieldError{Field: "limit", Code: "out_of_range", Message: "limit must be in range 1-100"})
+	}
+	if filter.Offset < 0 {
+		fields = append(fields, apperror.FieldError{Field: "offset", Code: "out_of_range", Message: "offset must not be negative"})
+	}
+	if filter.CareComplexityMin < 0 {
+		fields = append(fields, apperror.FieldError{Field: "care_complexity_min", Code: "out_of_range",
+			Message: "care_complexity_min must not be negative"})
+	}
+	if filter.CareComplexityMax < 0 {
+		fields = append(fields, apperror.FieldError{Field: "care_complexity_max", Code: "out_of_range",
+			Message: "care_complexity_max must not be negative"})
+	}
+	if filter.CareComplexityMax > 0 && filter.CareComplexityMin > filter.CareComplexityMax {
+		fields = append(fields, apperror.FieldError{Field: "care_complexity_min", Code: "out_of_range",
+			Message: "care_complexity_min must not exceed care_complexity_max"})
+	}
+	return fields
+}
+
+// Search Полнотекстовый поиск кошек. Запрос должен содержать от 1 до 200 символов
+func (s *CatService) Search(ctx context.Context, q string, limit int) (*[]entities.CatSearchResult, error) {
+	var fields []apperror.FieldError
+	q = strings.TrimSpace(q)
+	if q == "" || utf8.RuneCountInString(q) > 200 {
+		fields = append(fields, apperror.FieldError{Field: "q", Code: "invalid_length", Message: "query must be 1-200 characters long"})
+	}
+	if limit < 1 || limit > 100 {
+		fields = append(fields, apperror.FieldError{Field: "limit", Code: "out_of_range", Message: "limit must be in range 1-100"})
+	}
+	if len(fields) > 0 {
+		return nil, apperror.Validation("invalid_query", "invalid query parameters", fields...)
+	}
+
+	return s.cats.Search(ctx, q, limit)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"server/internal/entities"
+	"server/internal/repository"
+)
+
+// FavoriteService Правила работы с избранными кошками пользователя
+type FavoriteService struct {
+	favorites repository.FavoriteRepository
+	cats      repository.CatRepository
+}
+
+// NewFavoriteService Создание сервиса избранного
+func NewFavoriteService(favorites repository.FavoriteRepository, cats repository.CatRepository) *FavoriteService {
+	return &FavoriteService{favorites: favorites, cats: cats}
+}
+
+// List Избранные кошки пользователя
+func (s *FavoriteService) List(ctx context.Context, userID int) (*[]entities.FavoriteCat, error) {
+	return s.favorites.GetCats(ctx, userID)
+}
+
+// Add Добавление кошки в избранное. Кошка должна существовать и еще не быть в избранном
+func (s *FavoriteService) Add(ctx context.Context, userID, catID int) (*entities.Favorite, error) {
+	favorite := &entities.Favorite{UserID: userID, CatID: catID}
+
+	exists, err := s.favorites.Exists(ctx, favorite)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrFavoriteExists
+	}
+
+	if err := s.checkCat(ctx, catID); err != nil {
+		return nil, err
+	}
+
+	return s.favorites.Add(ctx, favorite)
+}
+
+// Remove Удаление кошки из избранного. Кошка должна существовать и быть в избранном
+func (s *FavoriteService) Remove(ctx context.Context, userID, catID int) error {
+	if err := s.checkCat(ctx, catID); err != nil {
+		return err
+	}
+
+	favorite := &entities.Favorite{UserID: userID, CatID: catID}
+	exists, err := s.favorites.Exists(ctx, favorite)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrFavoriteNotFound
+	}
+
+	return s.favorites.Remove(ctx, favorite)
+}
+
+func (s *FavoriteService) checkCat(ctx context.Context, catID int) error {
+	exists, err := s.cats.ExistsID(ctx, catID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCatNotFound
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"server/internal/apperror"
+	"server/internal/config"
+	"server/internal/mail"
+	"server/internal/repository"
+	"server/internal/storage"
+	"server/pkg"
+)
+
+// Ошибки сервисов. Вид ошибки (apperror.ErrNotFound, apperror.ErrConflict и т.д.) определяет
+// код ответа, поэтому ручки не проверяют конкретные ошибки
+var (
+	ErrCatExists          = apperror.Conflict("cat_exists", "cat already exists")
+	ErrCatNotFound        = apperror.NotFound("cat_not_found", "cat not exists")
+	ErrUserExists         = apperror.Conflict("user_exists", "user already exists")
+	ErrUserNotFound       = apperror.NotFound("user_not_found", "user not exists")
+	ErrInvalidCredentials = apperror.Unauthorized("invalid_credentials", "wrong data")
+	ErrInvalidRole        = apperror.Validation("invalid_role", "invalid role",
+		apperror.FieldError{Field: "role", Code: "invalid", Message: "role must be user, editor or admin"})
+	ErrFavoriteExists   = apperror.Conflict("favorite_exists", "favorite already exists")
+	ErrFavoriteNotFound = apperror.NotFound("favorite_not_found", "favorite not exists")
+)
+
+// Services Бизнес-логика приложения. Не зависит от HTTP и конкретной бд,
+// поэтому может тестироваться с хранилищами из repository/memory
+type Services struct {
+	Cats          *CatService
+	Users         *UserService
+	Favorites     *FavoriteService
+	Tokens        *TokenService
+	Verifications *VerificationService
+}
+
+// New Создание сервисов поверх набора хранилищ
+func New(repos repository.Repositories, st storage.Storage, jwt *pkg.JWT, mailer mail.Mailer,
+	cfg *config.Config) *Services {
+	return &Services{
+		Cats:          NewCatService(repos.Cats, st, cfg.Images),
+		Users:         NewUserService(repos.Users),
+		Favorites:     NewFavoriteService(repos.Favorites, repos.Cats),
+		Tokens:        NewTokenService(repos.Tokens, repos.Users, jwt, cfg.App),
+		Verifications: NewVerificationService(repos.EmailVerifications, jwt, mailer, cfg.Mail),
+	}
+}
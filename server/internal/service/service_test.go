@@ -0,0 +1,220 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"server/internal/apperror"
+	"server/internal/config"
+	"server/internal/entities"
+	"server/internal/repository"
+	"server/internal/repository/memory"
+	"server/internal/service"
+	"server/pkg"
+	"testing"
+)
+
+// newServices Сервисы поверх хранилищ в памяти с одной кошкой и одним пользователем
+func newServices(t *testing.T) (*service.Services, repository.Repositories, *entities.Cat, *entities.User) {
+	t.Helper()
+	ctx := context.Background()
+
+	repos := memory.NewRepositories()
+	cat, err := repos.Cats.Create(ctx, &entities.Cat{Breed: "Мейн-кун", Fur: "Длинношерстная", Temper: "Спокойный", CareComplexity: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := repos.Users.Create(ctx, &entities.User{Email: "petrov@mail.ru", Name: "Петр", Surname: "Петров", Role: entities.RoleUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{App: config.AppConfig{TokenExpiration: 1, RefreshTokenExpiration: 24}}
+
+	// Хранилище изображений и почта не нужны: кошки с занятой породой отклоняются до сохранения
+	// изображения, а письма в этих тестах не отправляются
+	return service.New(repos, nil, testJWT(t), nil, cfg), repos, cat, user
+}
+
+// testJWT Подпись токенов HS256 общим секретом: токены, выпущенные сервисами, проверяются
+// отдельно созданным экземпляром
+func testJWT(t *testing.T) *pkg.JWT {
+	t.Helper()
+
+	jwt, err := pkg.NewJWT("kotiki", []string{"kotiki"}, "", "", "test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jwt
+}
+
+func TestCatCreateDuplicateBreed(t *testing.T) {
+	services, _, cat, _ := newServices(t)
+
+	_, err := services.Cats.Create(context.Background(), &entities.Cat{Breed: cat.Breed}, nil)
+	if !errors.Is(err, apperror.ErrConflict) {
+		t.Fatalf("Create() error = %v, want %v", err, apperror.ErrConflict)
+	}
+}
+
+func TestUserSignUpDuplicateEmail(t *testing.T) {
+	services, _, _, user := newServices(t)
+
+	_, err := services.Users.SignUp(context.Background(), &entities.CreateUserRequest{
+		Email: user.Email, Password: "12345678", Name: "Иван", Surname: "Иванов",
+	})
+	if !errors.Is(err, apperror.ErrConflict) {
+		t.Fatalf("SignUp() error = %v, want %v", err, apperror.ErrConflict)
+	}
+}
+
+func TestFavorites(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(s *service.Services, cat *entities.Cat, user *entities.User) error
+		call    func(s *service.Services, cat *entities.Cat, user *entities.User) error
+		want    error
+	}{
+		{
+			name: "add",
+			call: func(s *service.Services, cat *entities.Cat, user *entities.User) error {
+				_, err := s.Favorites.Add(context.Background(), user.ID, cat.ID)
+				return err
+			},
+		},
+		{
+			name: "add existing",
+			prepare: func(s *service.Services, cat *entities.Cat, user *entities.User) error {
+				_, err := s.Favorites.Add(context.Background(), user.ID, cat.ID)
+				return err
+			},
+			call: func(s *service.Services, cat *entities.Cat, user *entities.User) error {
+				_, err := s.Favorites.Add(context.Background(), user.ID, cat.ID)
+				return err
+			},
+			want: apperror.ErrConflict,
+		},
+		{
+			name: "add missing cat",
+			call: func(s *service.Services, cat *entities.Cat, user *entities.User) error {
+				_, err := s.Favorites.Add(context.Background(), user.ID, cat.ID+100)
+				return err
+			},
+			want: apperror.ErrNotFound,
+		},
+		{
+			name: "remove missing favorite",
+			call: func(s *service.Services, cat *entities.Cat, user *entities.User) error {
+				return s.Favorites.Remove(context.Background(), user.ID, cat.ID)
+			},
+			want: apperror.ErrNotFound,
+		},
+		{
+			name: "remove missing cat",
+			call: func(s *service.Services, cat *entities.Cat, user *entities.User) error {
+				return s.Favorites.Remove(context.Background(), user.ID, cat.ID+100)
+			},
+			want: apperror.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services, _, cat, user := newServices(t)
+			if tt.prepare != nil {
+				if err := tt.prepare(services, cat, user); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := tt.call(services, cat, user)
+			if tt.want == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFavoriteRepositoryAddMissingCat(t *testing.T) {
+	_, repos, cat, user := newServices(t)
+
+	// Кошка удалена между проверкой в сервисе и добавлением: хранилище в памяти отвечает
+	// так же, как postgres при нарушении внешнего ключа
+	if err := repos.Cats.Delete(context.Background(), cat.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err := repos.Favorites.Add(context.Background(), &entities.Favorite{UserID: user.ID, CatID: cat.ID})
+	if !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("Add() error = %v, want %v", err, apperror.ErrNotFound)
+	}
+}
+
+func TestCatListCursorOrderMismatch(t *testing.T) {
+	services, repos, _, _ := newServices(t)
+	ctx := context.Background()
+	if _, err := repos.Cats.Create(ctx, &entities.Cat{Breed: "Сфинкс", Fur: "Бесшерстная", Temper: "Ласковый", CareComplexity: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := services.Cats.List(ctx, &entities.CatFilter{Sort: "id", Order: "asc", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("NextCursor is empty")
+	}
+
+	_, err = services.Cats.List(ctx, &entities.CatFilter{Sort: "id", Order: "desc", Limit: 1, Cursor: page.NextCursor})
+	if !errors.Is(err, repository.ErrInvalidCursor) {
+		t.Fatalf("List() error = %v, want %v", err, repository.ErrInvalidCursor)
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	services, _, _, user := newServices(t)
+	ctx := context.Background()
+
+	_, refreshToken, err := services.Tokens.Issue(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, rotated, err := services.Tokens.Refresh(ctx, refreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// Повторное предъявление использованного токена отзывает всю цепочку, в том числе новый токен
+	if _, _, err := services.Tokens.Refresh(ctx, refreshToken); !errors.Is(err, apperror.ErrUnauthorized) {
+		t.Fatalf("Refresh() reused token error = %v, want %v", err, apperror.ErrUnauthorized)
+	}
+	if _, _, err := services.Tokens.Refresh(ctx, rotated); !errors.Is(err, apperror.ErrUnauthorized) {
+		t.Fatalf("Refresh() after reuse error = %v, want %v", err, apperror.ErrUnauthorized)
+	}
+}
+
+func TestTokenLogout(t *testing.T) {
+	services, _, _, user := newServices(t)
+	ctx := context.Background()
+
+	accessToken, refreshToken, err := services.Tokens.Issue(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := pkg.ParseAccessToken(accessToken, testJWT(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := services.Tokens.Logout(ctx, token); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if !services.Tokens.Denylist().IsRevoked(token) {
+		t.Fatal("access token is not revoked after logout")
+	}
+	if _, _, err := services.Tokens.Refresh(ctx, refreshToken); !errors.Is(err, apperror.ErrUnauthorized) {
+		t.Fatalf("Refresh() after logout error = %v, want %v", err, apperror.ErrUnauthorized)
+	}
+}
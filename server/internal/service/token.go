@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"server/internal/apperror"
+	"server/internal/config"
+	"server/internal/entities"
+	"server/internal/log"
+	"server/internal/repository"
+	"server/pkg"
+	"server/util"
+	"time"
+)
+
+// TokenService Выпуск, ротация и отзыв токенов. Рефреш токен одноразовый: при обновлении выдается
+// новый токен той же цепочки ротаций, а повторное предъявление использованного токена отзывает
+// всю цепочку. Отзывы аксес токенов записываются в бд и сразу применяются в кэше denylist
+type TokenService struct {
+	tokens   repository.TokenRepository
+	users    repository.UserRepository
+	jwt      *pkg.JWT
+	cfg      config.AppConfig
+	denylist *pkg.Denylist
+}
+
+// NewTokenService Создание сервиса токенов с пустым кэшем отозванных токенов
+func NewTokenService(tokens repository.TokenRepository, users repository.UserRepository, jwt *pkg.JWT,
+	cfg config.AppConfig) *TokenService {
+	return &TokenService{tokens: tokens, users: users, jwt: jwt, cfg: cfg, denylist: pkg.NewDenylist()}
+}
+
+// Denylist Кэш отозванных аксес токенов для проверки при аутентификации
+func (s *TokenService) Denylist() *pkg.Denylist {
+	return s.denylist
+}
+
+// Issue Выпуск аксес и рефреш токенов новой сессии пользователя
+func (s *TokenService) Issue(ctx context.Context, user *entities.User) (string, string, error) {
+	sessionID, err := util.GenerateRandomString(16)
+	if err != nil {
+		return "", "", err
+	}
+
+	return s.issue(ctx, user.ID, user.Role, sessionID)
+}
+
+// Refresh Обмен рефреш токена на новую пару токенов той же сессии. Роль читается из бд,
+// чтобы изменения ролей применялись при обновлении токенов
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (string, string, error) {
+	userID, err := pkg.ParseRefreshToken(refreshToken, s.jwt)
+	if err != nil {
+		return "", "", invalidRefreshToken(err)
+	}
+
+	tokenHash := util.HashToken(refreshToken)
+	stored, err := s.tokens.UseRefresh(ctx, tokenHash)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return "", "", s.reject(ctx, tokenHash)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if stored.UserID != userID {
+		return "", "", invalidRefreshToken(errors.New("refresh token user mismatch"))
+	}
+
+	role, err := s.users.GetRoleByID(ctx, userID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return "", "", invalidRefreshToken(err)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return s.issue(ctx, userID, role, stored.FamilyID)
+}
+
+// issue Выпуск аксес токена и сохранение рефреш токена в цепочке ротаций familyID
+func (s *TokenService) issue(ctx context.Context, userID int, role, familyID string) (string, string, error) {
+	accessToken, err := pkg.GenerateAccessToken(userID, role, s.cfg.TokenExpiration, familyID, s.jwt)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := pkg.GenerateRefreshToken(userID, s.cfg.RefreshTokenExpiration, s.jwt)
+	if err != nil {
+		return "", "", err
+	}
+
+	stored := &entities.RefreshToken{
+		TokenHash: util.HashToken(refreshToken),
+		FamilyID:  familyID,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(time.Duration(s.cfg.RefreshTokenExpiration) * time.Hour),
+	}
+	if err := s.tokens.CreateRefresh(ctx, stored); err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// reject Ошибка для неприменимого рефреш токена. Если токен был выдан, но уже использован,
+// это повторное предъявление: вся цепочка токенов отзывается
+func (s *TokenService) reject(ctx context.Context, tokenHash string) error {
+	stored, err := s.tokens.GetRefreshByHash(ctx, tokenHash)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return err
+	}
+
+	if stored != nil && stored.UsedAt != nil {
+		if err := s.tokens.RevokeRefreshFamily(ctx, stored.FamilyID); err != nil {
+			return err
+		}
+
+		log.Ctx(ctx).Warn().Int("user_id", stored.UserID).Str("family_id", stored.FamilyID).
+			Msg("refresh token reuse detected, token family revoked")
+		return invalidRefreshToken(errors.New("refresh token reused"))
+	}
+
+	return invalidRefreshToken(errors.New("refresh token not found, revoked or expired"))
+}
+
+// Logout Отзыв аксес токена и всех рефреш токенов его сессии
+func (s *TokenService) Logout(ctx context.Context, token *pkg.AccessToken) error {
+	if err := s.tokens.RevokeAccess(ctx, token.ID, token.UserID, token.ExpiresAt); err != nil {
+		return err
+	}
+	s.denylist.RevokeToken(token.ID, token.ExpiresAt)
+
+	if token.SessionID == "" {
+		return nil
+	}
+	return s.tokens.RevokeRefreshFamily(ctx, token.SessionID)
+}
+
+// LogoutAll Отзыв всех аксес и рефреш токенов пользователя
+func (s *TokenService) LogoutAll(ctx context.Context, userID int) error {
+	if err := s.RevokeAccess(ctx, userID); err != nil {
+		return err
+	}
+	return s.tokens.RevokeRefreshUser(ctx, userID)
+}
+
+// RevokeAccess Отзыв всех выданных аксес токенов пользователя, например после смены роли.
+// Рефреш токены остаются действительными и выдают токены с актуальной ролью
+func (s *TokenService) RevokeAccess(ctx context.Context, userID int) error {
+	revokedAt, err := s.tokens.RevokeAccessUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	s.denylist.RevokeUser(userID, revokedAt)
+	return nil
+}
+
+// CleanupRevoked Удаление записей об уже истекших отозванных токенах
+func (s *TokenService) CleanupRevoked(ctx context.Context) error {
+	return s.tokens.CleanupRevokedAccess(ctx)
+}
+
+// SyncDenylist Загрузка отозванных токенов из бд в кэш, чтобы отзывы, сделанные другими
+// экземплярами бэкенда, вступали в силу
+func (s *TokenService) SyncDenylist(ctx context.Context) error {
+	tokens, users, err := s.tokens.GetRevokedAccess(ctx, time.Duration(s.cfg.TokenExpiration)*time.Hour)
+	if err != nil {
+		return err
+	}
+	s.denylist.Merge(tokens, users)
+	return nil
+}
+
+// invalidRefreshToken Ошибка 401 с одинаковым для клиента сообщением, причина попадает только в лог
+func invalidRefreshToken(reason error) error {
+	return apperror.Wrap(apperror.ErrUnauthorized, "invalid_refresh_token", "invalid refresh token", reason)
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"server/internal/apperror"
+	"server/internal/entities"
+	"server/internal/repository"
+	"server/util"
+)
+
+// UserService Правила регистрации, входа и управления ролями пользователей
+type UserService struct {
+	users repository.UserRepository
+}
+
+// NewUserService Создание сервиса пользователей
+func NewUserService(users repository.UserRepository) *UserService {
+	return &UserService{users: users}
+}
+
+// SignUp Регистрация пользователя с ролью user. Email должен быть уникальным
+func (s *UserService) SignUp(ctx context.Context, req *entities.CreateUserRequest) (*entities.User, error) {
+	exists, err := s.users.Exists(ctx, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrUserExists
+	}
+
+	hashedPassword, err := util.HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.users.Create(ctx, &entities.User{
+		Email:    req.Email,
+		Password: hashedPassword,
+		Name:     req.Name,
+		Surname:  req.Surname,
+		Role:     entities.RoleUser,
+	})
+}
+
+// Authenticate Проверка email и пароля. Для неизвестного email и неверного пароля
+// возвращается одна и та же ошибка ErrInvalidCredentials
+func (s *UserService) Authenticate(ctx context.Context, email, password string) (*entities.User, error) {
+	user, err := s.users.GetByEmail(ctx, email)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := util.CheckPassword(password, user.Password); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// GetByID Получение пользователя вместе с ролью и временем подтверждения email
+func (s *UserService) GetByID(ctx context.Context, id int) (*entities.User, error) {
+	user, err := s.users.GetByID(ctx, id)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetData Получение открытых данных пользователя
+func (s *UserService) GetData(ctx context.Context, id int) (*entities.UserData, error) {
+	exists, err := s.users.ExistsID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	return s.users.GetDataByID(ctx, id)
+}
+
+// GetRole Получение текущей роли пользователя
+func (s *UserService) GetRole(ctx context.Context, id int) (string, error) {
+	role, err := s.users.GetRoleByID(ctx, id)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return "", ErrUserNotFound
+	}
+	return role, err
+}
+
+// UpdateRole Назначение роли пользователю. Отзыв выданных токенов остается на вызывающем,
+// см. TokenService.RevokeAccess
+func (s *UserService) UpdateRole(ctx context.Context, id int, role string) error {
+	if !entities.ValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	exists, err := s.users.ExistsID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	return s.users.UpdateRole(ctx, id, role)
+}
+
+// UpdateRoleByEmail Назначение роли пользователю по email. Возвращает пользователя,
+// чтобы вызывающий мог отозвать его токены
+func (s *UserService) UpdateRoleByEmail(ctx context.Context, email, role string) (*entities.User, error) {
+	if !entities.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	user, err := s.users.GetByEmail(ctx, email)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.users.UpdateRole(ctx, user.ID, role); err != nil {
+		return nil, err
+	}
+	user.Role = role
+	return user, nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"server/internal/apperror"
+	"server/internal/config"
+	"server/internal/entities"
+	"server/internal/mail"
+	"server/internal/repository"
+	"server/pkg"
+	"server/util"
+	"time"
+)
+
+// VerificationService Подтверждение email по одноразовой ссылке из письма. В бд хранится
+// только хэш токена, токен действует mail.verification_expiration часов
+type VerificationService struct {
+	verifications repository.EmailVerificationRepository
+	jwt           *pkg.JWT
+	mailer        mail.Mailer
+	cfg           config.MailConfig
+}
+
+// NewVerificationService Создание сервиса подтверждения email
+func NewVerificationService(verifications repository.EmailVerificationRepository, jwt *pkg.JWT,
+	mailer mail.Mailer, cfg config.MailConfig) *VerificationService {
+	return &VerificationService{verifications: verifications, jwt: jwt, mailer: mailer, cfg: cfg}
+}
+
+// Send Выпуск одноразового токена подтверждения email и отправка ссылки с ним
+func (s *VerificationService) Send(ctx context.Context, user *entities.User) error {
+	token, err := pkg.GenerateEmailVerificationToken(user.ID, s.cfg.VerificationExpiration, s.jwt)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(time.Duration(s.cfg.VerificationExpiration) * time.Hour)
+	if err := s.verifications.Create(ctx, util.HashToken(token), user.ID, expiresAt); err != nil {
+		return err
+	}
+
+	link, err := url.Parse(s.cfg.VerificationURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Подтверждение email на Kotiki",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\n"+
+			"Чтобы подтвердить адрес электронной почты, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует %d ч. Если вы не регистрировались на Kotiki, просто проигнорируйте это письмо.\n",
+			user.Name, link, s.cfg.VerificationExpiration),
+	})
+}
+
+// Verify Подтверждение email владельца токена. Возвращает id пользователя. Использованный,
+// истекший и чужой токен отклоняются с одной и той же ошибкой, email при этом не подтверждается
+func (s *VerificationService) Verify(ctx context.Context, token string) (int, error) {
+	userID, err := pkg.ParseEmailVerificationToken(token, s.jwt)
+	if err != nil {
+		return 0, invalidVerificationToken(err)
+	}
+
+	err = s.verifications.Use(ctx, util.HashToken(token), userID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return 0, invalidVerificationToken(errors.New("verification token not found, used, expired or issued to another user"))
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// Cleanup Удаление истекших и использованных токенов подтверждения email
+func (s *VerificationService) Cleanup(ctx context.Context) error {
+	return s.verifications.Cleanup(ctx)
+}
+
+// invalidVerificationToken Ошибка 400 с одинаковым для клиента сообщением, причина попадает только в лог
+func invalidVerificationToken(reason error) error {
+	return apperror.Wrap(apperror.ErrBadRequest, "invalid_verification_token",
+		"invalid or expired verification token", reason)
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"server/internal/config"
+	"server/internal/entities"
+	"server/internal/images"
+)
+
+// SaveCatImages Создание размеров изображения и запись их в хранилище st.
+// Файлы именуются по хэшу содержимого, поэтому повторная загрузка того же файла не создает копий
+func SaveCatImages(ctx context.Context, st Storage, r io.Reader, cfg config.ImagesConfig) (entities.CatImages, error) {
+	processed, err := images.Process(r, images.Limits{
+		MaxBytes:       int64(cfg.MaxUploadSize),
+		MaxWidth:       cfg.MaxWidth,
+		MaxHeight:      cfg.MaxHeight,
+		MaxPixels:      cfg.MaxPixels,
+		AllowedFormats: cfg.AllowedFormats,
+	})
+	if err != nil {
+		return entities.CatImages{}, err
+	}
+
+	var res entities.CatImages
+	for _, p := range processed {
+		exists, err := st.Exists(ctx, p.Key)
+		if err != nil {
+			return entities.CatImages{}, err
+		}
+		if !exists {
+			err = st.Put(ctx, p.Key, bytes.NewReader(p.Data), int64(len(p.Data)), "image/jpeg")
+			if err != nil {
+				return entities.CatImages{}, err
+			}
+		}
+
+		switch p.Rendition {
+		case "thumbnail":
+			res.Thumbnail = p.Key
+		case "card":
+			res.Card = p.Key
+		case "full":
+			res.Full = p.Key
+		}
+	}
+
+	return res, nil
+}
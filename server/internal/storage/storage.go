@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"server/internal/config"
+)
+
+var (
+	// ErrNotFound объект с таким ключом отсутствует в хранилище
+	ErrNotFound = errors.New("object not found")
+	// ErrInvalidKey ключ содержит разделители пути или пуст
+	ErrInvalidKey = errors.New("invalid storage key")
+)
+
+// ObjectInfo Метаданные сохраненного объекта
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// Storage Хранилище файлов изображений. Ключ - имя файла без директорий
+type Storage interface {
+	// Put сохранение объекта; повторная запись по тому же ключу перезаписывает объект
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get чтение объекта, вызывающий обязан закрыть ReadCloser
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	// Exists проверка наличия объекта
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete удаление объекта; отсутствие объекта ошибкой не считается
+	Delete(ctx context.Context, key string) error
+}
+
+// probeKey Ключ служебного объекта, которым проверяется доступность хранилища на запись
+const probeKey = ".readiness-probe"
+
+// CheckWritable Проверка, что в хранилище можно записать и удалить объект
+func CheckWritable(ctx context.Context, s Storage) error {
+	probe := []byte("ok")
+	if err := s.Put(ctx, probeKey, bytes.NewReader(probe), int64(len(probe)), "text/plain"); err != nil {
+		return fmt.Errorf("failed to write probe object: %w", err)
+	}
+	if err := s.Delete(ctx, probeKey); err != nil {
+		return fmt.Errorf("failed to delete probe object: %w", err)
+	}
+	return nil
+}
+
+// New Создание хранилища по настройкам: local или s3
+func New(cfg config.StorageConfig) (Storage, error) {
+	switch cfg.Driver {
+	case "local":
+		return NewLocal(cfg.Dir)
+	case "s3":
+		return NewS3(cfg.S3)
+	}
+
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+}
+
+// validKey Ключ не должен выходить за пределы хранилища
+func validKey(key string) error {
+	if key == "" || key == "." || key == ".." {
+		return fmt.Errorf("%w %q", ErrInvalidKey, key)
+	}
+	for _, r := range key {
+		if r == '/' || r == '\\' || r == 0 {
+			return fmt.Errorf("%w %q", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"server/internal/apperror"
+	"server/internal/entities"
+	"strings"
+)
+
+// bcryptMaxLength Длина пароля в байтах, больше которой bcrypt не принимает
+const bcryptMaxLength = 72
+
+// validate Общий экземпляр проверки, кэширующий разобранные теги структур
+var validate = newValidator()
+
+// newValidator Настройка проверки: поля называются так же, как в запросе (json, form или query),
+// типы шерсти и темпераменты проверяются правилами fur и temper, длина пароля в байтах - правилом bcryptlen
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "query"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+
+	// Ошибки регистрации возможны только при пустом имени правила или функции
+	_ = v.RegisterValidation("fur", func(fl validator.FieldLevel) bool {
+		return entities.ValidFur(fl.Field().String())
+	})
+	_ = v.RegisterValidation("temper", func(fl validator.FieldLevel) bool {
+		return entities.ValidTemper(fl.Field().String())
+	})
+	// max считает символы, а bcrypt ограничивает байты: 37 кириллических символов уже не помещаются
+	_ = v.RegisterValidation("bcryptlen", func(fl validator.FieldLevel) bool {
+		return len(fl.Field().String()) <= bcryptMaxLength
+	})
+
+	return v
+}
+
+// Struct Проверка структуры по тегам validate. Возвращает apperror.ErrValidation
+// с ошибками всех некорректных полей
+func Struct(s interface{}) error {
+	err := validate.Struct(s)
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	fields := make([]apperror.FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		fields = append(fields, fieldError(fe))
+	}
+
+	return apperror.Validation("validation_failed", "request validation failed", fields...)
+}
+
+// fieldError Код и сообщение ошибки поля по нарушенному правилу
+func fieldError(fe validator.FieldError) apperror.FieldError {
+	field := fe.Field()
+	isString := fe.Kind() == reflect.String
+
+	switch fe.Tag() {
+	case "required":
+		return apperror.FieldError{Field: field, Code: "required", Message: field + " is required"}
+	case "email":
+		return apperror.FieldError{Field: field, Code: "invalid_email", Message: field + " must be a valid email address"}
+	case "min":
+		if isString {
+			return apperror.FieldError{Field: field, Code: "too_short",
+				Message: fmt.Sprintf("%s must be at least %s characters long", field, fe.Param())}
+		}
+		return apperror.FieldError{Field: field, Code: "out_of_range",
+			Message: fmt.Sprintf("%s must be at least %s", field, fe.Param())}
+	case "max":
+		if isString {
+			return apperror.FieldError{Field: field, Code: "too_long",
+				Message: fmt.Sprintf("%s must be at most %s characters long", field, fe.Param())}
+		}
+		return apperror.FieldError{Field: field, Code: "out_of_range",
+			Message: fmt.Sprintf("%s must be at most %s", field, fe.Param())}
+	case "oneof":
+		return apperror.FieldError{Field: field, Code: "not_allowed",
+			Message: fmt.Sprintf("%s must be one of: %s", field, strings.ReplaceAll(fe.Param(), " ", ", "))}
+	case "bcryptlen":
+		return apperror.FieldError{Field: field, Code: "too_long",
+			Message: fmt.Sprintf("%s must be at most %d bytes long", field, bcryptMaxLength)}
+	case "fur":
+		return apperror.FieldError{Field: field, Code: "not_allowed",
+			Message: fmt.Sprintf("%s must be one of: %s", field, strings.Join(entities.FurTypes, ", "))}
+	case "temper":
+		return apperror.FieldError{Field: field, Code: "not_allowed",
+			Message: fmt.Sprintf("%s must be one of: %s", field, strings.Join(entities.Tempers, ", "))}
+	}
+
+	return apperror.FieldError{Field: field, Code: "invalid", Message: field + " is invalid"}
+}
@@ -4,93 +4,239 @@ import (
 	"errors"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
+	"server/internal/apperror"
+	"server/internal/log"
+	"server/util"
+	"strconv"
 	"strings"
 	"time"
 )
 
-// tokenClaims Структура для полей токена
+// Типы токенов, записываемые в поле token_type
+const (
+	accessTokenType            = "access"
+	refreshTokenType           = "refresh"
+	emailVerificationTokenType = "email_verification"
+)
+
+// tokenClaims Структура для полей токена: стандартные поля JWT (RFC 7519) и поля приложения
 type tokenClaims struct {
-	jwt.MapClaims
-	UserId int `json:"user_id"`
+	jwt.RegisteredClaims
+	TokenType string `json:"token_type"`
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+}
+
+// JWT Параметры выпуска и проверки токенов
+type JWT struct {
+	Issuer   string
+	Audience []string
+	Keys     *KeySet
+}
+
+// AccessToken Данные проверенного аксес токена
+type AccessToken struct {
+	ID        string // jti
+	SessionID string // id цепочки рефреш токенов, к которой относится токен
+	UserID    int
+	Role      string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
 }
 
 // WithJWTAuth Middleware аутентификации
-func WithJWTAuth(c *fiber.Ctx, signingKey string) error {
+func WithJWTAuth(c *fiber.Ctx, j *JWT, denylist *Denylist) error {
 	header := c.Get("Authorization")
 
 	if header == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Missing auth token"})
+		return apperror.Unauthorized("missing_token", "Missing auth token")
 	}
 
 	tokenString := strings.Split(header, " ")
 
 	if len(tokenString) != 2 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid auth header"})
+		return apperror.Unauthorized("invalid_auth_header", "Invalid auth header")
 	}
 
-	id, err := ParseToken(tokenString[1], signingKey)
+	token, err := ParseAccessToken(tokenString[1], j)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		// Причина попадает только в лог, клиенту отдается одинаковое сообщение
+		return apperror.Wrap(apperror.ErrUnauthorized, "invalid_token", "invalid or expired token", err)
 	}
-	// Записываем id в контекст, чтобы в дальнейшем использовать в других функциях
-	c.Locals("id", id)
+	if denylist.IsRevoked(token) {
+		return apperror.Unauthorized("token_revoked", "Token has been revoked")
+	}
+	// Записываем id и токен в контекст, чтобы в дальнейшем использовать в других функциях
+	c.Locals("id", token.UserID)
+	c.Locals("token", token)
+	log.WithUserID(c, token.UserID)
 	return c.Next()
 }
 
-// GenerateAccessToken Генрация аксес токена
-func GenerateAccessToken(id, expirationTime int, signingKey string) (string, error) {
-	claims := &tokenClaims{
-		jwt.MapClaims{
-			"ExpiresAt": time.Now().Add(time.Duration(expirationTime) * time.Hour).Unix(),
-			"IssuedAr":  time.Now().Unix(),
-		},
-		id,
+// RequireRole Middleware проверки роли. Должен стоять после WithJWTAuth
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, ok := c.Locals("token").(*AccessToken)
+		if !ok {
+			return apperror.Unauthorized("missing_token", "Missing auth token")
+		}
+
+		for _, role := range roles {
+			if token.Role == role {
+				return c.Next()
+			}
+		}
+
+		return apperror.Forbidden("insufficient_permissions", "Insufficient permissions")
+	}
+}
+
+// GenerateAccessToken Генрация аксес токена с ролью пользователя, привязанного к сессии sessionID
+func GenerateAccessToken(id int, role string, expirationTime int, sessionID string, j *JWT) (string, error) {
+	claims, err := j.newClaims(id, expirationTime)
+	if err != nil {
+		return "", err
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	claims.TokenType = accessTokenType
+	claims.Role = role
+	claims.SessionID = sessionID
 
-	return token.SignedString([]byte(signingKey))
+	return j.Keys.sign(claims)
 }
 
-// GenerateRefreshToken Генерация рефреш токена
-func GenerateRefreshToken(id int, signingKey string) (string, error) {
-	claims := &tokenClaims{
-		jwt.MapClaims{
-			"ExpiresAt": time.Now().Add(724 * time.Hour).Unix(),
-			"IssuedAr":  time.Now().Unix(),
-		},
-		id,
+// GenerateRefreshToken Генерация рефреш токена. Случайный jti делает каждый токен уникальным,
+// так как на сервере хранится хэш токена
+func GenerateRefreshToken(id, expirationTime int, j *JWT) (string, error) {
+	claims, err := j.newClaims(id, expirationTime)
+	if err != nil {
+		return "", err
 	}
-	// Создание токена с параметрами записанными в claims и uid пользователя
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	claims.TokenType = refreshTokenType
 
-	return token.SignedString([]byte(signingKey))
+	return j.Keys.sign(claims)
 }
 
-// ParseToken Парсинг токена и получение id пользователя
-func ParseToken(tokenString string, signingKey string) (int, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &tokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return []byte(signingKey), nil
-	})
+// GenerateEmailVerificationToken Генерация токена для ссылки подтверждения email. Одноразовость
+// обеспечивается на сервере: хранится хэш токена и отметка об использовании
+func GenerateEmailVerificationToken(id, expirationTime int, j *JWT) (string, error) {
+	claims, err := j.newClaims(id, expirationTime)
+	if err != nil {
+		return "", err
+	}
+	claims.TokenType = emailVerificationTokenType
+
+	return j.Keys.sign(claims)
+}
 
+// newClaims Заполнение стандартных полей токена, expirationTime задается в часах
+func (j *JWT) newClaims(id, expirationTime int) (*tokenClaims, error) {
+	jti, err := util.GenerateRandomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.Itoa(id),
+			Issuer:    j.Issuer,
+			Audience:  j.Audience,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(expirationTime) * time.Hour)),
+		},
+	}, nil
+}
+
+// ParseToken Парсинг аксес токена и получение id пользователя
+func ParseToken(tokenString string, j *JWT) (int, error) {
+	token, err := ParseAccessToken(tokenString, j)
 	if err != nil {
 		return 0, err
 	}
 
-	if !token.Valid {
+	return token.UserID, nil
+}
+
+// ParseAccessToken Парсинг аксес токена
+func ParseAccessToken(tokenString string, j *JWT) (*AccessToken, error) {
+	claims, userID, err := j.parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != accessTokenType {
+		return nil, errors.New("not an access token")
+	}
+
+	return &AccessToken{
+		ID:        claims.ID,
+		SessionID: claims.SessionID,
+		UserID:    userID,
+		Role:      claims.Role,
+		IssuedAt:  claims.IssuedAt.Time,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// ParseRefreshToken Парсинг рефреш токена и получение id пользователя
+func ParseRefreshToken(tokenString string, j *JWT) (int, error) {
+	claims, userID, err := j.parseClaims(tokenString)
+	if err != nil {
 		return 0, err
 	}
 
-	claims, ok := token.Claims.(*tokenClaims)
-	if !ok {
+	if claims.TokenType != refreshTokenType {
+		return 0, errors.New("not a refresh token")
+	}
+
+	return userID, nil
+}
+
+// ParseEmailVerificationToken Парсинг токена подтверждения email и получение id пользователя
+func ParseEmailVerificationToken(tokenString string, j *JWT) (int, error) {
+	claims, userID, err := j.parseClaims(tokenString)
+	if err != nil {
 		return 0, err
 	}
 
-	if time.Now().Unix() > int64(claims.MapClaims["ExpiresAt"].(float64)) {
-		return 0, errors.New("Token has expired")
+	if claims.TokenType != emailVerificationTokenType {
+		return 0, errors.New("not an email verification token")
+	}
+
+	return userID, nil
+}
+
+// parseClaims Проверка подписи, срока действия, издателя и аудитории токена
+func (j *JWT) parseClaims(tokenString string) (*tokenClaims, int, error) {
+	claims := &tokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, j.Keys.keyFunc)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !token.Valid {
+		return nil, 0, errors.New("invalid token")
+	}
+
+	// exp и iat необязательны по RFC 7519, но у наших токенов они должны быть всегда
+	if claims.ExpiresAt == nil || claims.IssuedAt == nil || claims.ID == "" {
+		return nil, 0, errors.New("invalid token claims")
+	}
+	if !claims.VerifyIssuer(j.Issuer, true) {
+		return nil, 0, errors.New("invalid token issuer")
+	}
+	for _, aud := range j.Audience {
+		if !claims.VerifyAudience(aud, true) {
+			return nil, 0, errors.New("invalid token audience")
+		}
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, 0, errors.New("invalid token subject")
 	}
 
-	return claims.UserId, nil
+	return claims, userID, nil
 }
@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"sync"
+	"time"
+)
+
+// Denylist Кэш отозванных аксес токенов. Проверка выполняется только в памяти,
+// актуальность между несколькими бэкендами поддерживается периодической синхронизацией с бд
+type Denylist struct {
+	mu sync.RWMutex
+	// tokens отозванные токены: jti -> время истечения токена
+	tokens map[string]time.Time
+	// revokedBefore токены пользователя, выпущенные не позже этого времени, недействительны
+	revokedBefore map[int]time.Time
+}
+
+// NewDenylist Создание пустого кэша отозванных токенов
+func NewDenylist() *Denylist {
+	return &Denylist{
+		tokens:        make(map[string]time.Time),
+		revokedBefore: make(map[int]time.Time),
+	}
+}
+
+// RevokeToken Отзыв одного токена до момента его истечения
+func (d *Denylist) RevokeToken(jti string, expiresAt time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.tokens[jti] = expiresAt
+}
+
+// RevokeUser Отзыв всех токенов пользователя, выпущенных не позже момента at
+func (d *Denylist) RevokeUser(userID int, at time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if at.After(d.revokedBefore[userID]) {
+		d.revokedBefore[userID] = at
+	}
+}
+
+// Merge Добавление отзывов, загруженных из бд. Отзыв необратим, поэтому записи только добавляются,
+// а уже истекшие токены удаляются из кэша
+func (d *Denylist) Merge(tokens map[string]time.Time, revokedBefore map[int]time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for jti, expiresAt := range tokens {
+		d.tokens[jti] = expiresAt
+	}
+	for userID, at := range revokedBefore {
+		if at.After(d.revokedBefore[userID]) {
+			d.revokedBefore[userID] = at
+		}
+	}
+
+	now := time.Now()
+	for jti, expiresAt := range d.tokens {
+		if expiresAt.Before(now) {
+			delete(d.tokens, jti)
+		}
+	}
+}
+
+// IsRevoked Проверка, отозван ли токен
+func (d *Denylist) IsRevoked(token *AccessToken) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if token.ID != "" {
+		if _, ok := d.tokens[token.ID]; ok {
+			return true
+		}
+	}
+
+	// Время выпуска в токене хранится в целых секундах, а время отзыва точнее. Токен, выпущенный
+	// в ту же секунду, что и отзыв, мог быть выпущен до него, поэтому граница округляется вверх
+	if at, ok := d.revokedBefore[token.UserID]; ok && !token.IssuedAt.After(ceilSecond(at)) {
+		return true
+	}
+
+	return false
+}
+
+// ceilSecond Округление времени вверх до целой секунды
+func ceilSecond(t time.Time) time.Time {
+	ceil := t.Truncate(time.Second)
+	if ceil.Before(t) {
+		ceil = ceil.Add(time.Second)
+	}
+	return ceil
+}
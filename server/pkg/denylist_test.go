@@ -0,0 +1,34 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDenylistRevokeUser(t *testing.T) {
+	revokedAt := time.Date(2024, 5, 1, 12, 0, 10, 300_000_000, time.UTC)
+	d := NewDenylist()
+	d.RevokeUser(1, revokedAt)
+
+	tests := []struct {
+		name     string
+		userID   int
+		issuedAt time.Time
+		want     bool
+	}{
+		{name: "issued before", userID: 1, issuedAt: revokedAt.Add(-time.Minute).Truncate(time.Second), want: true},
+		{name: "issued in the same second", userID: 1, issuedAt: revokedAt.Truncate(time.Second), want: true},
+		{name: "issued in the rounded up second", userID: 1, issuedAt: revokedAt.Truncate(time.Second).Add(time.Second), want: true},
+		{name: "issued after", userID: 1, issuedAt: revokedAt.Truncate(time.Second).Add(2 * time.Second), want: false},
+		{name: "other user", userID: 2, issuedAt: revokedAt.Add(-time.Minute).Truncate(time.Second), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &AccessToken{UserID: tt.userID, IssuedAt: tt.issuedAt}
+			if got := d.IsRevoked(token); got != tt.want {
+				t.Fatalf("IsRevoked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package pkg
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// signingKey Ключ подписи токенов
+type signingKey struct {
+	id     string
+	method jwt.SigningMethod
+	// private ключ подписи; для HS256 это секрет, для RS256/EdDSA закрытый ключ
+	private interface{}
+	// public ключ проверки подписи; для HS256 совпадает с секретом
+	public interface{}
+}
+
+// KeySet Набор ключей подписи. Токены подписываются активным ключом, а проверяются любым
+// ключом набора по kid, что позволяет выполнять ротацию без разлогина пользователей
+type KeySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+// NewHMACKeySet Набор из одного симметричного ключа HS256. Такие ключи не публикуются в JWKS
+func NewHMACKeySet(secret string) *KeySet {
+	key := &signingKey{
+		method:  jwt.SigningMethodHS256,
+		private: []byte(secret),
+		public:  []byte(secret),
+	}
+
+	return &KeySet{active: key, keys: map[string]*signingKey{"": key}}
+}
+
+// LoadKeySet Загрузка закрытых ключей RSA или Ed25519 в формате PEM из директории dir.
+// Имя файла без расширения .pem используется как kid, activeID задает ключ для подписи
+func LoadKeySet(dir, activeID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no *.pem keys found in %s", dir)
+	}
+
+	ks := &KeySet{keys: make(map[string]*signingKey)}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		id := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := parsePrivateKey(id, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key %s: %w", path, err)
+		}
+		ks.keys[id] = key
+	}
+
+	active, ok := ks.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found in %s", activeID, dir)
+	}
+	ks.active = active
+
+	return ks, nil
+}
+
+// parsePrivateKey Определение типа ключа и алгоритма подписи
+func parsePrivateKey(id string, data []byte) (*signingKey, error) {
+	if key, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		return &signingKey{id: id, method: jwt.SigningMethodRS256, private: key, public: &key.PublicKey}, nil
+	}
+
+	if key, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("unsupported EdDSA key")
+		}
+		return &signingKey{id: id, method: jwt.SigningMethodEdDSA, private: edKey, public: edKey.Public()}, nil
+	}
+
+	return nil, errors.New("key must be an RSA or Ed25519 private key in PEM format")
+}
+
+// sign Подпись токена активным ключом
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.method, claims)
+	if ks.active.id != "" {
+		token.Header["kid"] = ks.active.id
+	}
+
+	return token.SignedString(ks.active.private)
+}
+
+// keyFunc Выбор ключа проверки по kid. Алгоритм токена обязан совпадать с алгоритмом ключа,
+// иначе возможна подмена алгоритма
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("invalid signing method")
+	}
+
+	return key.public, nil
+}
+
+// JWK Открытый ключ в формате JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet Набор открытых ключей для /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS Открытые ключи набора. Симметричные ключи не публикуются
+func (ks *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		jwk, ok := publicJWK(key)
+		if ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+
+	return set
+}
+
+func publicJWK(key *signingKey) (JWK, bool) {
+	encode := base64.RawURLEncoding.EncodeToString
+
+	switch pub := key.public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: key.id,
+			Use: "sig",
+			Alg: key.method.Alg(),
+			N:   encode(pub.N.Bytes()),
+			E:   encode(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: key.id,
+			Use: "sig",
+			Alg: key.method.Alg(),
+			Crv: "Ed25519",
+			X:   encode(pub),
+		}, true
+	}
+
+	return JWK{}, false
+}
+
+// NewJWT Создание параметров выпуска токенов. Если keysDir не задан, используется HS256 с секретом secret
+func NewJWT(issuer string, audience []string, keysDir, activeKeyID, secret string) (*JWT, error) {
+	j := &JWT{Issuer: issuer, Audience: audience}
+
+	if keysDir == "" {
+		j.Keys = NewHMACKeySet(secret)
+		return j, nil
+	}
+
+	keys, err := LoadKeySet(keysDir, activeKeyID)
+	if err != nil {
+		return nil, err
+	}
+	j.Keys = keys
+
+	return j, nil
+}